@@ -0,0 +1,223 @@
+package compiler
+
+import (
+	"strings"
+)
+
+// aggregateFuncs lists aggregate functions of field tokens (count:sum)
+var aggregateFuncs = map[string]bool{"count": true, "sum": true, "avg": true, "min": true, "max": true}
+
+// selection describes shape of selected rows - distinct and grouping parameters
+type selection struct {
+	isDistinct bool
+	distinctOn []string // distinct on fields
+	fields     []string // selected fields tokens, nil means all model fields
+	groupBy    []string // group fields tokens
+	isGrouped  bool     // selection is grouped or contains aggregate functions
+}
+
+// isSelected reports whether field token is in select list
+func (sel *selection) isSelected(field string) bool {
+	if sel.fields == nil {
+		return true
+	}
+	for _, f := range sel.fields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isGroupedField reports whether field token of grouped selection is group field or aggregate function
+func (sel *selection) isGroupedField(field string) bool {
+	if isAggregate(field) {
+		return true
+	}
+	for _, g := range sel.groupBy {
+		if g == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fieldResolver resolves field tokens and relations of conditions into sql expressions, empty expression means unexpected field
+type fieldResolver interface {
+	fieldExpr(name string) string
+	relation(name string) *relation
+}
+
+// havingFields resolves field tokens of having conditions, which may contain aggregate functions
+type havingFields struct {
+	*Schema
+}
+
+// fieldExpr returns sql expression of aggregate function or field, date truncation is not supported in having conditions
+func (h havingFields) fieldExpr(name string) string {
+	if isDateBucket(name) {
+		return ""
+	}
+
+	return h.valueExpr(name)
+}
+
+// relation returns nil, as relation conditions reference ungrouped rows
+func (h havingFields) relation(name string) *relation {
+	return nil
+}
+
+// splitFieldFunc splits field token (count:sum) into field name and function, empty function means plain field
+func splitFieldFunc(token string) (field, fn string) {
+	i := strings.Index(token, ":")
+	if i < 0 {
+		return token, ""
+	}
+
+	return token[:i], token[i+1:]
+}
+
+// isFieldFunc reports whether sort token part is a field function instead of selection order
+func isFieldFunc(fn string) bool {
+	return aggregateFuncs[fn] || dateUnits[fn]
+}
+
+// isAggregate reports whether field token is aggregate function of field
+func isAggregate(token string) bool {
+	_, fn := splitFieldFunc(token)
+	return aggregateFuncs[fn]
+}
+
+// aggregateExpr returns sql expression and column alias of aggregate field token (count:sum or :count),
+// empty expression means unexpected token
+func (s *Schema) aggregateExpr(token string) (expr, alias string) {
+	field, fn := splitFieldFunc(token)
+	if !aggregateFuncs[fn] {
+		return "", ""
+	}
+	if field == "" { // count of rows
+		if fn != "count" {
+			return "", ""
+		}
+		return "count(*)", "count"
+	}
+
+	f := s.fieldExpr(field)
+	if f == "" {
+		return "", ""
+	}
+
+	return fn + "(" + f + ")", s.columnAlias(field) + "_" + fn
+}
+
+// funcExpr returns sql expression and column alias of field function token - aggregate function or date truncation
+func (s *Schema) funcExpr(token string) (expr, alias string) {
+	if isDateBucket(token) {
+		return s.bucketExpr(token)
+	}
+
+	return s.aggregateExpr(token)
+}
+
+// valueExpr returns sql expression of field token, which may be field function
+func (s *Schema) valueExpr(token string) string {
+	if _, fn := splitFieldFunc(token); isFieldFunc(fn) {
+		expr, _ := s.funcExpr(token)
+		return expr
+	}
+
+	return s.fieldExpr(token)
+}
+
+// parseSelection reads distinct selection of fields block and group fields of group block,
+// returns selection with fields block without distinct element
+func parseSelection(fieldsBlock, groupBlock string) (*selection, string, error) {
+	isDistinct, on, fields, err := parseDistinct(fieldsBlock)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sel := &selection{isDistinct: isDistinct, distinctOn: on}
+	if groupBlock != "" {
+		sel.groupBy = strings.Split(strings.ReplaceAll(groupBlock, " ", ""), ",")
+		sel.isGrouped = true
+	}
+	for _, f := range strings.Split(fields, ",") {
+		if isAggregate(strings.TrimSpace(f)) {
+			sel.isGrouped = true
+		}
+		if fields != "" {
+			sel.fields = append(sel.fields, strings.TrimSpace(f))
+		}
+	}
+
+	return sel, fields, nil
+}
+
+// combineGroup assembles GROUP BY query block expression and checks that non-aggregated selected fields are grouped
+func combineGroup(s *Schema, sel *selection, fields string) (string, error) {
+	if !sel.isGrouped {
+		return "", nil
+	}
+
+	var exprs []string
+	grouped := make(map[string]bool, len(sel.groupBy))
+	for _, g := range sel.groupBy {
+		expr := s.fieldExpr(g)
+		if isDateBucket(g) {
+			expr, _ = s.bucketExpr(g)
+		}
+		if expr == "" {
+			return "", newError("Passed unexpected field name in group - " + g)
+		}
+		grouped[g] = true
+		exprs = append(exprs, expr)
+	}
+
+	var selected []string
+	if fields == "" { // all model fields are selected
+		selected = sortMap(s.fieldsMap)
+	} else {
+		selected = strings.Split(fields, ",")
+	}
+	for _, f := range selected {
+		f = strings.TrimSpace(f)
+		if isRelationBlock(f) { // related rows are aggregated per selected row
+			name, _, _ := decodeRelationBlock(f)
+			return "", newError("Passed relation selection with grouping - " + name)
+		}
+		if !isAggregate(f) && !grouped[f] {
+			return "", newError("Passed ungrouped field in select - " + f)
+		}
+	}
+
+	return strings.Join(exprs, ", "), nil
+}
+
+// combineHaving assembles HAVING query block expression of conditions on aggregate functions and group fields,
+// returns it with arguments and index of the next argument placeholder
+func combineHaving(s *Schema, d Dialect, conds string, sel *selection, index int, withArgs bool) (string, []interface{}, int, error) {
+	conds = strings.ReplaceAll(conds, " ", "")
+	if conds == "" {
+		return "", nil, index, nil
+	}
+	if !sel.isGrouped {
+		return "", nil, 0, newError("Passed having conditions without grouping")
+	}
+
+	var condIndex *int
+	if withArgs {
+		condIndex = &index
+	}
+	preparedConds, args, condIndex, err := extractConditionsSet(havingFields{s}, d, conds, false, condIndex)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	if !withArgs { // values are inlined into query
+		return strings.Join(preparedConds, " "), nil, index, nil
+	}
+
+	return strings.Join(preparedConds, " "), args, *condIndex, nil
+}
@@ -0,0 +1,114 @@
+package compiler
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testAggregateCases = []struct {
+	// Compile params
+	Opts   []Option
+	Params string // query string, %s is replaced by cursor of content field
+
+	// Compile response
+	MainQuery  string
+	CountQuery string
+	Args       []interface{}
+	Err        error
+}{
+	{ // 1. Test count of rows by group field
+		Params:    "content,:count???content",
+		MainQuery: "select q.content, count(*) as count from v_test q group by q.content",
+	},
+	{ // 2. Test sum with conditions, having conditions and order by aggregate
+		Opts:       []Option{WithCount(CountExact)},
+		Params:     "content,count:sum?ID>1?count:sum,desc,10,?content?count:sum>100",
+		MainQuery:  "select q.content, sum(q.count) as count_sum from v_test q where q.id > $1 group by q.content having sum(q.count) > $2 order by sum(q.count) desc, q.content desc limit 10",
+		CountQuery: "select count(*) from (select 1 from v_test q where q.id > $1 group by q.content having sum(q.count) > $2) q",
+		Args:       []interface{}{1, 100},
+	},
+	{ // 3. Test aggregates without group fields
+		Params:    "ID:count,count:avg,count:min,count:max??",
+		MainQuery: "select count(q.id) as id_count, avg(q.count) as count_avg, min(q.count) as count_min, max(q.count) as count_max from v_test q",
+	},
+	{ // 4. Test having conditions with inline values
+		Opts:      []Option{WithArgs(false)},
+		Params:    "isBool,:count???isBool?isBool==true*:count>=2",
+		MainQuery: "select q.is_bool, count(*) as count from v_test q group by q.is_bool having q.is_bool = true and count(*) >= 2",
+	},
+	{ // 5. Test cursor of grouped selection in having conditions
+		Params:    "content,:count??content,asc,10,,%s?content?:count>1",
+		MainQuery: "select q.content, count(*) as count from v_test q group by q.content having (count(*) > $1) and (q.content) > ($2) order by q.content asc limit 10",
+		Args:      []interface{}{1, "abc"},
+	},
+	{ // 6. Test window count of groups
+		Opts:      []Option{WithCount(CountWindow)},
+		Params:    "content,:count???content",
+		MainQuery: "select q.content, count(*) as count, count(*) over() as total_count from v_test q group by q.content",
+	},
+	{ // 7. Test ERROR ungrouped field in select
+		Params: "ID,content,:count???content",
+		Err:    newError("Passed ungrouped field in select - ID"),
+	},
+	{ // 8. Test ERROR aggregate in conditions
+		Params: "content,:count?count:sum>1??content",
+		Err:    newError("Passed unexpected field name in condition - count:sum"),
+	},
+	{ // 9. Test ERROR having conditions without grouping
+		Params: "ID????ID>1",
+		Err:    newError("Passed having conditions without grouping"),
+	},
+	{ // 10. Test ERROR aggregate of unexpected field
+		Params: "title:sum??",
+		Err:    newError("Passed unexpected field name in select - title:sum"),
+	},
+	{ // 11. Test ERROR unexpected group field
+		Params: "???title",
+		Err:    newError("Passed unexpected field name in group - title"),
+	},
+	{ // 12. Test ERROR ungrouped sort field
+		Params: "content,:count??ID,asc,10,?content",
+		Err:    newError("Passed ungrouped field in order - ID"),
+	},
+	{ // 13. Test ERROR sort field of aggregate selection without group fields
+		Params: "count:sum??content,desc,,",
+		Err:    newError("Passed ungrouped field in order - content"),
+	},
+}
+
+func TestAggregate(t *testing.T) {
+	token, _ := EncodeCursor([]string{"content"}, []interface{}{"abc"})
+
+	for index, c := range testAggregateCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			params := strings.Replace(c.Params, "%s", token, 1)
+			res, err := New(c.Opts...).Compile(context.Background(), Request{Model: TestModel{}, Target: "v_test", Params: params})
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if res.MainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, res.MainQuery)
+				t.Fail()
+			}
+			if res.CountQuery != c.CountQuery {
+				t.Errorf("expected countQ: %v, got: %v", c.CountQuery, res.CountQuery)
+				t.Fail()
+			}
+			if !reflect.DeepEqual(res.Args, c.Args) {
+				t.Errorf("expected args: %v, got: %v", c.Args, res.Args)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package compiler
+
+// dateUnits lists date truncation units of timestamp field tokens (createdAt:month)
+var dateUnits = map[string]bool{
+	"second":  true,
+	"minute":  true,
+	"hour":    true,
+	"day":     true,
+	"week":    true,
+	"month":   true,
+	"quarter": true,
+	"year":    true,
+}
+
+// isDateBucket reports whether field token is date truncation of field
+func isDateBucket(token string) bool {
+	_, fn := splitFieldFunc(token)
+	return dateUnits[fn]
+}
+
+// bucketExpr returns sql expression and column alias of date truncation field token (createdAt:month),
+// empty expression means unexpected token or field of non-timestamp type
+func (s *Schema) bucketExpr(token string) (expr, alias string) {
+	field, unit := splitFieldFunc(token)
+	if !dateUnits[unit] || !s.isTimestamp(field) {
+		return "", ""
+	}
+
+	f := s.fieldExpr(field)
+	if f == "" {
+		return "", ""
+	}
+
+	return "date_trunc('" + unit + "', " + f + ")", s.columnAlias(field) + "_" + unit
+}
+
+// hasDateBuckets reports whether any of field tokens is date truncation of field
+func hasDateBuckets(tokens []string) bool {
+	for _, token := range tokens {
+		if isDateBucket(token) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,79 @@
+package compiler
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type TestEventModel struct {
+	ID        *int64       `json:"ID,omitempty" sql:"id"`
+	Title     *string      `json:"title,omitempty" sql:"title"`
+	CreatedAt *time.Time   `json:"createdAt,omitempty" sql:"created_at"`
+	ClosedAt  sql.NullTime `json:"closedAt,omitempty" sql:"closed_at"`
+}
+
+var testBucketCases = []struct {
+	// Compile params
+	Opts   []Option
+	Params string
+
+	// Compile response
+	MainQuery string
+	Err       error
+}{
+	{ // 1. Test month buckets in select, group and order
+		Params:    "createdAt:month,:count??createdAt:month:desc,,,?createdAt:month",
+		MainQuery: "select date_trunc('month', q.created_at) as created_at_month, count(*) as count from v_events q group by date_trunc('month', q.created_at) order by date_trunc('month', q.created_at) desc",
+	},
+	{ // 2. Test day buckets of nullable timestamp without grouping
+		Params:    "closedAt:day??closedAt:day,asc,,",
+		MainQuery: "select date_trunc('day', q.closed_at) as closed_at_day from v_events q order by date_trunc('day', q.closed_at) asc",
+	},
+	{ // 3. Test week buckets in ClickHouse dialect
+		Opts:      []Option{WithDialect(ClickHouse)},
+		Params:    "createdAt:week,:count???createdAt:week",
+		MainQuery: "select date_trunc('week', q.created_at) as created_at_week, count(*) as count from v_events q group by date_trunc('week', q.created_at)",
+	},
+	{ // 4. Test ERROR buckets of non-timestamp field
+		Params: "title:month??",
+		Err:    newError("Passed unexpected field name in select - title:month"),
+	},
+	{ // 5. Test ERROR buckets of non-timestamp group field
+		Params: ":count???title:day",
+		Err:    newError("Passed unexpected field name in group - title:day"),
+	},
+	{ // 6. Test ERROR buckets in conditions
+		Params: "ID?createdAt:day==1?",
+		Err:    newError("Passed unexpected field name in condition - createdAt:day"),
+	},
+	{ // 7. Test ERROR buckets in MySQL dialect
+		Opts:   []Option{WithDialect(MySQL)},
+		Params: "ID??createdAt:year,,,",
+		Err:    newError("Passed unsupported date truncation in MySQL dialect"),
+	},
+}
+
+func TestBuckets(t *testing.T) {
+	for index, c := range testBucketCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			res, err := New(c.Opts...).Compile(context.Background(), Request{Model: TestEventModel{}, Target: "v_events", Params: c.Params})
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if res.MainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, res.MainQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package compiler
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Characters of SQaLice grammar, which can not be passed in field names
+const reservedValueChars = "?*|,()"
+
+// Characters of SQaLice operators, which can not be passed in condition fields and values in addition to reservedValueChars.
+// Spaces are pruned from conditions block, so they are reserved too
+const reservedConditionChars = reservedValueChars + "=!<>~^{} "
+
+// QueryBuilder assembles SQaLice query with typed calls instead of string concatenation
+type QueryBuilder struct {
+	fields     []string
+	distinct   bool
+	distinctOn []string
+	where      *Cond
+	sortFields []string
+	sortOrder  string
+	limit      *int
+	offset     *int
+	cursor     string
+	groupBy    []string
+	having     *Cond
+}
+
+// Cond describes single condition or set of conditions joined with one logical operator.
+// Conditions block supports one level of brackets, so set may contain sets of single conditions only
+type Cond struct {
+	FieldName string
+	Operator  string
+	Value     interface{}
+
+	SepOperator string  // logical operator of conditions set
+	Conds       []*Cond // conditions set
+}
+
+// Query starts a new query builder
+func Query() *QueryBuilder {
+	return &QueryBuilder{}
+}
+
+// Select adds fields to the fields block
+func (b *QueryBuilder) Select(fields ...string) *QueryBuilder {
+	b.fields = append(b.fields, fields...)
+	return b
+}
+
+// Distinct requests selection of distinct rows
+func (b *QueryBuilder) Distinct() *QueryBuilder {
+	b.distinct = true
+	return b
+}
+
+// DistinctOn requests selection of the first row of each group of passed fields values
+func (b *QueryBuilder) DistinctOn(fields ...string) *QueryBuilder {
+	b.distinctOn = append(b.distinctOn, fields...)
+	return b
+}
+
+// Where adds condition to the conditions block with AND separator
+func (b *QueryBuilder) Where(c *Cond) *QueryBuilder {
+	if b.where == nil {
+		b.where = c
+	} else {
+		b.where = b.where.And(c)
+	}
+	return b
+}
+
+// OrderBy adds sort fields to the restrictions block
+func (b *QueryBuilder) OrderBy(fields ...string) *QueryBuilder {
+	b.sortFields = append(b.sortFields, fields...)
+	return b
+}
+
+// Asc sets ascending selection order
+func (b *QueryBuilder) Asc() *QueryBuilder {
+	b.sortOrder = "asc"
+	return b
+}
+
+// Desc sets descending selection order
+func (b *QueryBuilder) Desc() *QueryBuilder {
+	b.sortOrder = "desc"
+	return b
+}
+
+// Limit sets selection limit
+func (b *QueryBuilder) Limit(limit int) *QueryBuilder {
+	b.limit = &limit
+	return b
+}
+
+// Offset sets selection offset
+func (b *QueryBuilder) Offset(offset int) *QueryBuilder {
+	b.offset = &offset
+	return b
+}
+
+// After sets cursor of keyset selection, encoded by NextCursor
+func (b *QueryBuilder) After(cursor string) *QueryBuilder {
+	b.cursor = cursor
+	return b
+}
+
+// GroupBy adds fields to the group block
+func (b *QueryBuilder) GroupBy(fields ...string) *QueryBuilder {
+	b.groupBy = append(b.groupBy, fields...)
+	return b
+}
+
+// Having adds condition to the having block with AND separator
+func (b *QueryBuilder) Having(c *Cond) *QueryBuilder {
+	if b.having == nil {
+		b.having = c
+	} else {
+		b.having = b.having.And(c)
+	}
+	return b
+}
+
+// Params serializes builder into SQaLice query string, which is compiled by the same parser as client queries.
+// Returns error for conditions sets nested deeper than one level and for values containing grammar characters
+func (b *QueryBuilder) Params() (string, error) {
+	for _, f := range append(append(append(b.fields, b.sortFields...), b.distinctOn...), b.groupBy...) {
+		if f == "" || strings.ContainsAny(f, reservedValueChars) {
+			return "", newError("Passed unexpected field name in query builder - " + f)
+		}
+	}
+	if b.limit != nil && *b.limit < 0 {
+		return "", newError("Invalid negative selection limit - " + strconv.Itoa(*b.limit))
+	}
+	if b.offset != nil && *b.offset < 0 {
+		return "", newError("Invalid negative selection offset - " + strconv.Itoa(*b.offset))
+	}
+
+	if strings.ContainsAny(b.cursor, reservedValueChars) {
+		return "", newError("Passed unexpected cursor in query builder - " + b.cursor)
+	}
+
+	condsBlock := ""
+	if b.where != nil {
+		var err error
+		condsBlock, err = b.where.params(true)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	restsBlock := ""
+	if b.sortFields != nil || b.sortOrder != "" || b.limit != nil || b.offset != nil || b.cursor != "" {
+		rests := []string{strings.Join(b.sortFields, "|"), b.sortOrder, "", ""}
+		if b.limit != nil {
+			rests[2] = strconv.Itoa(*b.limit)
+		}
+		if b.offset != nil {
+			rests[3] = strconv.Itoa(*b.offset)
+		}
+		if b.cursor != "" {
+			rests = append(rests, b.cursor)
+		}
+		restsBlock = strings.Join(rests, ",")
+	}
+
+	fields := b.fields
+	if b.distinctOn != nil {
+		fields = append([]string{"distinct(" + strings.Join(b.distinctOn, "|") + ")"}, fields...)
+	} else if b.distinct {
+		fields = append([]string{"distinct"}, fields...)
+	}
+
+	blocks := []string{strings.Join(fields, ","), condsBlock, restsBlock}
+	if b.groupBy != nil || b.having != nil {
+		havingBlock := ""
+		if b.having != nil {
+			var err error
+			havingBlock, err = b.having.params(true)
+			if err != nil {
+				return "", err
+			}
+		}
+		blocks = append(blocks, strings.Join(b.groupBy, ","), havingBlock)
+	}
+
+	return strings.Join(blocks, "?"), nil
+}
+
+// String returns SQaLice query string of builder, empty string means invalid query
+func (b *QueryBuilder) String() string {
+	params, _ := b.Params()
+	return params
+}
+
+// Get builds a GET query from builder, NULL conditions do not add arguments unlike arguments of Get function
+func (b *QueryBuilder) Get(model interface{}, target string, withCount, withArgs bool) (mainQ, countQ string, args []interface{}, er error) {
+	params, err := b.Params()
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return newCompat(withCount, withArgs).compileQueries(Request{Model: model, Target: target, Params: params})
+}
+
+// Eq returns EQUALS condition
+func Eq(field string, value interface{}) *Cond {
+	return &Cond{FieldName: field, Operator: "==", Value: value}
+}
+
+// NotEq returns NOT EQUALS condition
+func NotEq(field string, value interface{}) *Cond {
+	return &Cond{FieldName: field, Operator: "!=", Value: value}
+}
+
+// Lt returns LESS condition
+func Lt(field string, value interface{}) *Cond {
+	return &Cond{FieldName: field, Operator: "<", Value: value}
+}
+
+// Lte returns LESS OR EQUALS condition
+func Lte(field string, value interface{}) *Cond {
+	return &Cond{FieldName: field, Operator: "<=", Value: value}
+}
+
+// Gt returns GREATER condition
+func Gt(field string, value interface{}) *Cond {
+	return &Cond{FieldName: field, Operator: ">", Value: value}
+}
+
+// Gte returns GREATER OR EQUALS condition
+func Gte(field string, value interface{}) *Cond {
+	return &Cond{FieldName: field, Operator: ">=", Value: value}
+}
+
+// Overlaps returns OVERLAPS condition
+func Overlaps(field string, value interface{}) *Cond {
+	return &Cond{FieldName: field, Operator: ">>", Value: value}
+}
+
+// NotOverlaps returns NOT OVERLAPS condition
+func NotOverlaps(field string, value interface{}) *Cond {
+	return &Cond{FieldName: field, Operator: "!!", Value: value}
+}
+
+// Includes returns INCLUDES condition
+func Includes(field string, value interface{}) *Cond {
+	return &Cond{FieldName: field, Operator: "->>", Value: value}
+}
+
+// And joins conditions with AND logical operator, sets with OR operator inside AND set are placed in brackets
+func (c *Cond) And(conds ...*Cond) *Cond {
+	return c.join("*", conds)
+}
+
+// Or joins conditions with OR logical operator, sets with AND operator inside OR set are placed in brackets
+func (c *Cond) Or(conds ...*Cond) *Cond {
+	return c.join("||", conds)
+}
+
+// join joins conditions into set, merging sets with the same logical operator
+func (c *Cond) join(op string, conds []*Cond) *Cond {
+	set := &Cond{SepOperator: op}
+	for _, cond := range append([]*Cond{c}, conds...) {
+		if cond.SepOperator == op {
+			set.Conds = append(set.Conds, cond.Conds...)
+		} else {
+			set.Conds = append(set.Conds, cond)
+		}
+	}
+
+	return set
+}
+
+// params serializes condition into conditions block. Nested sets are placed in brackets
+// before single conditions, as it is required by conditions block grammar
+func (c *Cond) params(isTop bool) (string, error) {
+	if c.SepOperator == "" {
+		return c.conditionParams()
+	}
+	if !isTop {
+		return "", newError("Passed unsupported nested conditions set in query builder")
+	}
+	if logicalBindings[c.SepOperator] == "" {
+		return "", newError("Passed unexpected logical operator in query builder - " + c.SepOperator)
+	}
+
+	var bracketConds, conds []string
+	for _, cond := range c.Conds {
+		if cond.SepOperator == "" {
+			p, err := cond.conditionParams()
+			if err != nil {
+				return "", err
+			}
+			conds = append(conds, p)
+			continue
+		}
+
+		var setConds []string
+		for _, setCond := range cond.Conds {
+			p, err := setCond.params(false)
+			if err != nil {
+				return "", err
+			}
+			setConds = append(setConds, p)
+		}
+		bracketConds = append(bracketConds, "("+strings.Join(setConds, cond.SepOperator)+")")
+	}
+
+	return strings.Join(append(bracketConds, conds...), c.SepOperator), nil
+}
+
+// conditionParams serializes single condition
+func (c *Cond) conditionParams() (string, error) {
+	if c.FieldName == "" || strings.ContainsAny(c.FieldName, reservedConditionChars) {
+		return "", newError("Passed unexpected field name in query builder - " + c.FieldName)
+	}
+
+	isOperatorCorrect := false
+	for _, key := range mathOperatorsList { // check condition operator
+		if c.Operator == key {
+			isOperatorCorrect = true
+		}
+	}
+	if !isOperatorCorrect {
+		return "", newError("Passed incorrect operator in query condition - " + c.Operator)
+	}
+
+	value, err := formatBuilderValue(c.Value)
+	if err != nil {
+		return "", err
+	}
+
+	return c.FieldName + c.Operator + value, nil
+}
+
+// formatBuilderValue converts condition value into conditions block format
+func formatBuilderValue(value interface{}) (string, error) {
+	if value == nil {
+		return "null", nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "null", nil
+		}
+		return formatBuilderValue(v.Elem().Interface())
+	}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		if v.Len() == 0 {
+			return "", newError("Passed empty array value in query builder")
+		}
+
+		var values []string
+		for i := 0; i < v.Len(); i++ {
+			el, err := formatBuilderValue(v.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			values = append(values, el)
+		}
+		return strings.Join(values, ","), nil
+	}
+
+	str := fmt.Sprint(value)
+	if str == "" || strings.ContainsAny(str, reservedConditionChars) {
+		return "", newError("Passed value with reserved characters in query builder - " + str)
+	}
+
+	return str, nil
+}
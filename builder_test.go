@@ -0,0 +1,142 @@
+package compiler
+
+import (
+	"strconv"
+	"testing"
+)
+
+var testQueryBuilderCases = []struct {
+	// Builder params
+	Builder *QueryBuilder
+
+	// Builder response
+	Params    string
+	MainQuery string
+	Args      []interface{}
+	Err       error
+}{
+	{ // 1. Test empty builder
+		Builder:   Query(),
+		Params:    "??",
+		MainQuery: "select q.id, q.content, q.count, q.extra_field, q.is_bool, q.one_more_field from v_test q",
+	},
+	{ // 2. Test fields and single condition
+		Builder:   Query().Select("ID", "content").Where(Eq("ID", 1)),
+		Params:    "ID,content?ID==1?",
+		MainQuery: "select q.id, q.content from v_test q where q.id = $1",
+		Args:      []interface{}{1},
+	},
+	{ // 3. Test conditions set with bracket block
+		Builder:   Query().Select("ID").Where(Eq("ID", 1).Or(Eq("isBool", true))).Where(NotEq("content", "anth")),
+		Params:    "ID?(ID==1||isBool==true)*content!=anth?",
+		MainQuery: "select q.id from v_test q where (q.id = $1 or q.is_bool = $2) and q.content != $3",
+		Args:      []interface{}{1, true, "anth"},
+	},
+	{ // 4. Test single conditions are placed after bracket blocks
+		Builder:   Query().Select("ID").Where(Gt("count", 2).And(Eq("ID", []int{1, 2}).Or(Eq("ID", nil)))),
+		Params:    "ID?(ID==1,2||ID==null)*count>2?",
+		MainQuery: "select q.id from v_test q where (q.id = any($1) or q.id is null) and q.count > $2",
+		Args:      []interface{}{[]int{1, 2}, 2},
+	},
+	{ // 5. Test restrictions block
+		Builder:   Query().Select("ID").OrderBy("ID", "isBool").Desc().Limit(10).Offset(20),
+		Params:    "ID??ID|isBool,desc,10,20",
+		MainQuery: "select q.id from v_test q order by q.id desc, q.is_bool desc limit 10 offset 20",
+	},
+	{ // 6. Test partial restrictions block
+		Builder:   Query().Select("ID").Where(Lte("count", 5)).Limit(10),
+		Params:    "ID?count<=5?,,10,",
+		MainQuery: "select q.id from v_test q where q.count <= $1 limit 10",
+		Args:      []interface{}{5},
+	},
+	{ // 7. Test ERROR value with reserved characters
+		Builder: Query().Select("ID").Where(Eq("content", "a?b")),
+		Err:     newError("Passed value with reserved characters in query builder - a?b"),
+	},
+	{ // 8. Test ERROR value with operator characters
+		Builder: Query().Select("ID").Where(Eq("content", "a^^b")),
+		Err:     newError("Passed value with reserved characters in query builder - a^^b"),
+	},
+	{ // 9. Test ERROR value with relation block characters
+		Builder: Query().Select("ID").Where(NotEq("content", "x{y}")),
+		Err:     newError("Passed value with reserved characters in query builder - x{y}"),
+	},
+	{ // 10. Test ERROR array value with comparison operator
+		Builder: Query().Select("ID").Where(Eq("content", []string{"a", "b<=c"})),
+		Err:     newError("Passed value with reserved characters in query builder - b<=c"),
+	},
+	{ // 11. Test ERROR field name with operator characters
+		Builder: Query().Select("ID").Where(Eq("ID=1", 2)),
+		Err:     newError("Passed unexpected field name in query builder - ID=1"),
+	},
+	{ // 12. Test ERROR nested conditions set
+		Builder: Query().Where(Eq("ID", 1).And(Eq("ID", 2).Or(Eq("ID", 3).And(Eq("count", 1))))),
+		Err:     newError("Passed unsupported nested conditions set in query builder"),
+	},
+	{ // 13. Test ERROR incorrect operator
+		Builder: Query().Where(&Cond{FieldName: "ID", Operator: "^=", Value: 1}),
+		Err:     newError("Passed incorrect operator in query condition - ^="),
+	},
+	{ // 14. Test distinct on selection
+		Builder:   Query().DistinctOn("content").Select("ID", "content").OrderBy("ID").Desc(),
+		Params:    "distinct(content),ID,content??ID,desc,,",
+		MainQuery: "select distinct on (q.content) q.id, q.content from v_test q order by q.content desc, q.id desc",
+	},
+	{ // 15. Test group and having blocks
+		Builder:   Query().Select("content", ":count").GroupBy("content").Having(Gt(":count", 1)),
+		Params:    "content,:count???content?:count>1",
+		MainQuery: "select q.content, count(*) as count from v_test q group by q.content having count(*) > $1",
+		Args:      []interface{}{1},
+	},
+	{ // 16. Test INCLUDES condition
+		Builder:   Query().Select("ID").Where(Includes("ID", []int{1, 2})),
+		Params:    "ID?ID->>1,2?",
+		MainQuery: "select q.id from v_test q where q.id @> $1",
+		Args:      []interface{}{[]int{1, 2}},
+	},
+}
+
+func TestQueryBuilder(t *testing.T) {
+	for index, c := range testQueryBuilderCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			params, err := c.Builder.Params()
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+			if params != c.Params {
+				t.Errorf("expected params: %v, got: %v", c.Params, params)
+				t.Fail()
+			}
+
+			mainQuery, _, args, err := c.Builder.Get(TestModel{}, "v_test", false, true)
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+			if mainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, mainQuery)
+				t.Fail()
+			}
+			if len(args) != len(c.Args) {
+				t.Errorf("expected args: %v, got: %v", c.Args, args)
+				t.FailNow()
+			}
+			for i, arg := range c.Args {
+				if _, ok := arg.([]int); ok {
+					continue
+				}
+				if i >= len(args) || args[i] != arg {
+					t.Errorf("expected args: %v, got: %v", c.Args, args)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package compiler
+
+// clickhouseDialect describes ClickHouse syntax. Array is passed as a single argument
+type clickhouseDialect struct{}
+
+func (clickhouseDialect) Name() string {
+	return "ClickHouse"
+}
+
+func (clickhouseDialect) Placeholder(index int) string {
+	return "?"
+}
+
+func (clickhouseDialect) ExpandArrays() bool {
+	return false
+}
+
+func (clickhouseDialect) ArrayValues(values string) string {
+	return "[" + values + "]"
+}
+
+func (clickhouseDialect) InArray(field, values string, isNot bool) string {
+	if isNot {
+		return "not has(" + values + ", " + field + ")"
+	}
+
+	return "has(" + values + ", " + field + ")"
+}
+
+func (clickhouseDialect) Overlaps(field, values string, isNot bool) (string, error) {
+	if isNot {
+		return "not hasAny(" + field + ", " + values + ")", nil
+	}
+
+	return "hasAny(" + field + ", " + values + ")", nil
+}
+
+func (clickhouseDialect) Includes(field, values string) (string, error) {
+	return "hasAll(" + field + ", " + values + ")", nil
+}
+
+func (clickhouseDialect) JSONField(field, key string) string {
+	return "JSONExtractString(" + field + ", '" + key + "')"
+}
+
+func (clickhouseDialect) Like(field, pattern string) string {
+	return "toString(" + field + ") ilike " + pattern
+}
+
+func (clickhouseDialect) SortExpr(field, order, nulls string) string {
+	return sortExpr(field, order, nulls)
+}
@@ -0,0 +1,199 @@
+package compiler
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+var testClickHouseGetCases = []struct {
+	// Get params
+	Target    string
+	Params    string
+	Search    string
+	Scopes    []Scope
+	WithCount bool
+	WithArgs  bool
+
+	// Get response
+	MainQuery  string
+	CountQuery string
+	Args       []interface{}
+	Err        error
+}{
+	{ // 1. Test empty params blocks
+		Target:    "v_test",
+		Params:    "??",
+		WithCount: true,
+		WithArgs:  false,
+
+		MainQuery:  "select q.id, q.content, q.count, q.extra_field, q.is_bool, q.one_more_field from v_test q",
+		CountQuery: "select count(*) from (select 1 from v_test q) q",
+	},
+	{ // 2. Test fields params block with 3 fields
+		Target:    "v_test",
+		Params:    "ID,content,count??",
+		WithCount: false,
+		WithArgs:  false,
+
+		MainQuery: "select q.id, q.content, q.count from v_test q",
+	},
+	{ // 3. Test simple condition with inline value
+		Target:    "v_test",
+		Params:    "ID?ID==1?",
+		WithCount: true,
+		WithArgs:  false,
+
+		MainQuery:  "select q.id from v_test q where q.id = 1",
+		CountQuery: "select count(*) from (select 1 from v_test q where q.id = 1) q",
+	},
+	{ // 4. Test conditions with arguments
+		Target:    "v_test",
+		Params:    "ID?(ID==1||isBool==true)*content!=test?",
+		WithCount: false,
+		WithArgs:  true,
+
+		MainQuery: "select q.id from v_test q where (q.id = ? or q.is_bool = ?) and q.content != ?",
+		Args:      []interface{}{1, true, "test"},
+	},
+	{ // 5. Test array condition with inline values
+		Target:    "v_test",
+		Params:    "ID?ID==1,2,3?",
+		WithCount: false,
+		WithArgs:  false,
+
+		MainQuery: "select q.id from v_test q where has([1,2,3], q.id)",
+	},
+	{ // 6. Test array conditions with arguments
+		Target:    "v_test",
+		Params:    "ID?ID==1,2*count!=3,4?",
+		WithCount: false,
+		WithArgs:  true,
+
+		MainQuery: "select q.id from v_test q where has(?, q.id) and not has(?, q.count)",
+		Args:      []interface{}{[]int{1, 2}, []int{3, 4}},
+	},
+	{ // 7. Test OVERLAPS and NOT OVERLAPS operators
+		Target:    "v_test",
+		Params:    "ID?content>>a,b*extraField!!c,d?",
+		WithCount: false,
+		WithArgs:  true,
+
+		MainQuery: "select q.id from v_test q where hasAny(q.content, ?) and not hasAny(q.extra_field, ?)",
+		Args:      []interface{}{[]string{"a", "b"}, []string{"c", "d"}},
+	},
+	{ // 8. Test nested JSON field
+		Target:    "v_test",
+		Params:    "ID?content==key^^val?",
+		WithCount: false,
+		WithArgs:  true,
+
+		MainQuery: "select q.id from v_test q where JSONExtractString(q.content, 'key') = ?",
+		Args:      []interface{}{"val"},
+	},
+	{ // 9. Test null condition
+		Target:    "v_test",
+		Params:    "ID?content==null?",
+		WithCount: false,
+		WithArgs:  true,
+
+		MainQuery: "select q.id from v_test q where q.content is null",
+	},
+	{ // 10. Test search conditions
+		Target:    "v_test",
+		Params:    "ID??",
+		Search:    "content~~smth||extraField~~key^^val",
+		WithCount: false,
+		WithArgs:  true,
+
+		MainQuery: "select q.id from v_test q where (toString(q.content) ilike ? or toString(JSONExtractString(q.extra_field, 'key')) ilike ?)",
+		Args:      []interface{}{"%smth%", "%val%"},
+	},
+	{ // 11. Test INCLUDES array scope
+		Target:    "v_test",
+		Params:    "ID?ID==1?",
+		Scopes:    []Scope{{Column: "tags", Operator: "->>", Value: []string{"a", "b"}}},
+		WithCount: false,
+		WithArgs:  true,
+
+		MainQuery: "select q.id from v_test q where hasAll(q.tags, ?) and (q.id = ?)",
+		Args:      []interface{}{[]string{"a", "b"}, 1},
+	},
+	{ // 12. Test INCLUDES array condition
+		Target:    "v_test",
+		Params:    "ID?content->>a,b?",
+		WithCount: false,
+		WithArgs:  true,
+
+		MainQuery: "select q.id from v_test q where hasAll(q.content, ?)",
+		Args:      []interface{}{[]string{"a", "b"}},
+	},
+	{ // 13. Test INCLUDES condition with inline single value
+		Target:    "v_test",
+		Params:    "ID?count->>1?",
+		WithCount: false,
+		WithArgs:  false,
+
+		MainQuery: "select q.id from v_test q where hasAll(q.count, [1])",
+	},
+	{ // 14. Test restrictions block
+		Target:    "v_test",
+		Params:    "ID??ID|count,desc,10,20",
+		WithCount: true,
+		WithArgs:  false,
+
+		MainQuery:  "select q.id from v_test q order by q.id desc, q.count desc limit 10 offset 20",
+		CountQuery: "select count(*) from (select 1 from v_test q) q",
+	},
+	{ // 15. Test ERROR unexpected field name
+		Target:    "v_test",
+		Params:    "ID?title==1?",
+		WithCount: false,
+		WithArgs:  true,
+
+		Err: newError("Passed unexpected field name in condition - title"),
+	},
+}
+
+func TestClickHouseGet(t *testing.T) {
+	for index, c := range testClickHouseGetCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			countMode := CountNone
+			if c.WithCount {
+				countMode = CountExact
+			}
+
+			res, err := New(WithDialect(ClickHouse), WithArgs(c.WithArgs), WithCount(countMode)).Compile(context.Background(), Request{
+				Model:  TestModel{},
+				Target: c.Target,
+				Params: c.Params,
+				Search: c.Search,
+				Scopes: c.Scopes,
+			})
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if res.MainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, res.MainQuery)
+				t.Fail()
+			}
+			if c.WithCount && res.CountQuery != c.CountQuery {
+				t.Errorf("expected countQ: %v, got: %v", c.CountQuery, res.CountQuery)
+				t.Fail()
+			}
+			if c.Args != nil && !reflect.DeepEqual(res.Args, c.Args) {
+				t.Errorf("expected args: %v, got: %v", c.Args, res.Args)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -1,11 +1,10 @@
 package compiler
 
 import (
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
-
-	"github.com/lib/pq"
 )
 
 // Logical bindings between SQaLice and PG
@@ -27,227 +26,762 @@ var operatorBindings = map[string]string{
 	"->>": "->>", // INCLUDES
 }
 
+// Allowed format of column names passed by server code
+var columnNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Precompiled expressions of conditions parser
+var (
+	bracketConditionsRegexp = regexp.MustCompile(`\(.*?(=|~|\|).*?\)`) // \((.*?)\)
+	searchInjectionsRegexp  = regexp.MustCompile(`[^а-яА-Яa-zA-Z0-9',№^ ]+`)
+	injectionsRegexp        = regexp.MustCompile(`[^а-яА-Яa-zA-Z0-9{}_',-^ ]+`)
+)
+
 // Bindings for null field values
 var nullOperatorBindings = map[string]string{
 	"==": "=",  // EQUALS
 	"!=": "!=", // NOT EQUALS
 }
 
+// Scope describes server-side predicate, which is always applied to the query
+// and can not be bypassed by client conditions
+type Scope struct {
+	Column   string      // target column name
+	Operator string      // SQaLice math operator
+	Value    interface{} // predicate value, always passed as query argument
+}
+
+// PageLimits describes selection restrictions enforced on every query of compiler, zero value disables restriction
+type PageLimits struct {
+	DefaultLimit int // limit applied if query does not contain one
+	MaxLimit     int // upper bound of selection limit
+	MaxOffset    int // upper bound of selection offset
+}
+
+// validate checks consistency of page limits
+func (l PageLimits) validate() error {
+	if l.DefaultLimit < 0 || l.MaxLimit < 0 || l.MaxOffset < 0 {
+		return newError("Invalid negative page limits")
+	}
+	if l.MaxLimit > 0 && l.DefaultLimit > l.MaxLimit {
+		return newError("Default selection limit exceeds maximum limit")
+	}
+
+	return nil
+}
+
 // Get builds a GET query with parameters
 func Get(model interface{}, target, params string, withCount, withArgs bool) (mainQ, countQ string, args []interface{}, er error) {
-	return compile(model, target, params, withCount, withArgs, "")
+	return compileCompat(Request{Model: model, Target: target, Params: params}, withCount, withArgs)
 }
 
 // Search builds a GET query with LIKE filter on searchField
 func Search(model interface{}, target, params string, withCount, withArgs bool, searchParams string) (mainQ, countQ string, args []interface{}, er error) {
-	return compile(model, target, params, withCount, withArgs, searchParams)
+	return compileCompat(Request{Model: model, Target: target, Params: params, Search: searchParams}, withCount, withArgs)
+}
+
+// GetScoped builds a GET query with parameters, restricted by mandatory scopes
+func GetScoped(model interface{}, target, params string, withCount, withArgs bool, scopes []Scope) (mainQ, countQ string, args []interface{}, er error) {
+	return compileCompat(Request{Model: model, Target: target, Params: params, Scopes: scopes}, withCount, withArgs)
+}
+
+// SearchScoped builds a GET query with LIKE filter on searchField, restricted by mandatory scopes
+func SearchScoped(model interface{}, target, params string, withCount, withArgs bool, searchParams string, scopes []Scope) (mainQ, countQ string, args []interface{}, er error) {
+	return compileCompat(Request{Model: model, Target: target, Params: params, Search: searchParams, Scopes: scopes}, withCount, withArgs)
+}
+
+// compileCompat compiles request with positional parameters of Get and Search
+func compileCompat(req Request, withCount, withArgs bool) (mainQ, countQ string, args []interface{}, er error) {
+	c := newCompat(withCount, withArgs)
+	c.nullArgs = true // arguments of Get and Search contain nil values of NULL conditions
+
+	return c.compileQueries(req)
+}
+
+// newCompat creates compiler with positional parameters of Get and Search
+func newCompat(withCount, withArgs bool) *Compiler {
+	countMode := CountNone
+	if withCount {
+		countMode = CountExact
+	}
+
+	return New(WithArgs(withArgs), WithCount(countMode))
+}
+
+// compileQueries compiles request into main query, count query and arguments of main query
+func (c *Compiler) compileQueries(req Request) (mainQ, countQ string, args []interface{}, er error) {
+	res, err := c.compile(req)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if c.countMode != CountNone && res.SeekArgs != nil { // count query does not use arguments of cursor
+		return "", "", nil, newError("Passed cursor with count query, use Compile with Result.CountArgs")
+	}
+
+	return res.MainQuery, res.CountQuery, res.Args, nil
 }
 
 // compile assembles a query strings to PG database for main query and count query
-func compile(model interface{}, target, params string, withCount, withArgs bool, searchParams string) (mainQ, countQ string, args []interface{}, er error) {
-	if params == "" {
-		return "", "", nil, newError("Request parameters is not passed")
+func (c *Compiler) compile(req Request) (*Result, error) {
+	if req.Params == "" {
+		return nil, newError("Request parameters is not passed")
 	}
 
-	// form fields map with formDinamicModel
-	fieldsMap := formDinamicModel(model)
+	// form model schema with formSchema
+	s, err := formSchema(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	target := req.Target
+	if target == "" { // use target of registered model
+		target = s.Target
+	}
 
+	params, err := encodeRelationBlocks(req.Params)
+	if err != nil {
+		return nil, err
+	}
 	queryBlocks := strings.Split(params, "?")
-	selectBlock, err := combineFields(fieldsMap, queryBlocks[0])
+	groupBlock, havingBlock := "", ""
+	if len(queryBlocks) > 3 {
+		groupBlock = queryBlocks[3]
+	}
+	if len(queryBlocks) > 4 {
+		havingBlock = queryBlocks[4]
+	}
+	sel, fields, err := parseSelection(queryBlocks[0], groupBlock)
 	if err != nil {
-		return "", "", nil, err
+		return nil, err
+	}
+	distinctBlock, err := combineDistinct(s, sel)
+	if err != nil {
+		return nil, err
+	}
+	if sel.distinctOn != nil && c.dialect != PostgreSQL {
+		return nil, newError("Passed unsupported DISTINCT ON in " + c.dialect.Name() + " dialect")
+	}
+	if c.dialect != PostgreSQL && hasRelationSelections(strings.Split(fields, ",")) {
+		return nil, newError("Passed unsupported relation selection in " + c.dialect.Name() + " dialect")
+	}
+	if c.dialect == ClickHouse && hasRelationConditions(queryBlocks[1]) { // correlated subqueries are not supported
+		return nil, newError("Passed unsupported relation condition in " + c.dialect.Name() + " dialect")
+	}
+	groupByBlock, err := combineGroup(s, sel, fields)
+	if err != nil {
+		return nil, err
 	}
 
 	fromBlock, err := combineTarget(target)
 	if err != nil {
-		return "", "", nil, err
+		return nil, err
 	}
 
-	whereBlock, args, err := combineConditions(fieldsMap, queryBlocks[1], searchParams, withArgs)
+	d, err := c.queryDialect()
 	if err != nil {
-		return "", "", nil, err
+		return nil, err
+	}
+	if d.Placeholder(1) == d.Placeholder(2) && hasRelationSelections(strings.Split(fields, ",")) { // arguments of select are not the first ones
+		return nil, newError("Passed unsupported relation selection with positional placeholders")
 	}
 
-	limitsBlock, err := combineRestrictions(fieldsMap, queryBlocks[2])
+	whereBlock, args, nextIndex, err := combineConditions(s, d, queryBlocks[1], req.Search, req.Scopes, c.withArgs)
 	if err != nil {
-		return "", "", nil, err
+		return nil, err
 	}
 
-	var respArray []string
-	queryArray := []string{selectBlock, fromBlock, whereBlock, limitsBlock}
-	for _, block := range queryArray {
-		if block == "" {
-			continue
-		}
+	limits := c.limits
+	if limits == (PageLimits{}) { // page limits of registered model
+		limits = s.config.pageLimits
+	}
+	rests, err := combineRestrictions(s, d, queryBlocks[2], sel, limits)
+	if err != nil {
+		return nil, err
+	}
+	if c.dialect != PostgreSQL && c.dialect != ClickHouse &&
+		(hasDateBuckets(strings.Split(fields, ",")) || hasDateBuckets(sel.groupBy) || hasDateBuckets(rests.fields)) {
+		return nil, newError("Passed unsupported date truncation in " + c.dialect.Name() + " dialect")
+	}
+	if rests.cursor != nil && !c.withArgs {
+		return nil, newError("Passed cursor without arguments mode")
+	}
+	havingBlock, havingArgs, nextIndex, err := combineHaving(s, d, havingBlock, sel, nextIndex, c.withArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	// arguments of relation selections follow arguments of conditions
+	selectBlock, selectArgs, nextIndex, err := combineFields(s, d, fields, nextIndex, c.withArgs)
+	if err != nil {
+		return nil, err
+	}
 
-		respArray = append(respArray, strings.TrimSpace(block))
+	// arguments of cursor are the last ones, so count queries without cursor use the rest arguments
+	var (
+		seekBlock string
+		seekArgs  []interface{}
+	)
+	if rests.cursor != nil { // select rows or groups after cursor position
+		seekBlock, seekArgs = rests.cursor.condition(d, rests, nextIndex)
+	}
+	whereArgs := args
+	if havingArgs != nil || selectArgs != nil {
+		args = append(append(append([]interface{}{}, whereArgs...), havingArgs...), selectArgs...)
+	}
+	countArgs := args
+	if seekArgs != nil {
+		args = append(append([]interface{}{}, countArgs...), seekArgs...)
+	}
+	if c.placeholders == PlaceholderNamed { // arguments are bound by name
+		whereArgs, havingArgs, selectArgs, seekArgs = namedArgs(whereArgs), namedArgs(havingArgs), namedArgs(selectArgs), namedArgs(seekArgs)
+		countArgs, args = namedArgs(countArgs), namedArgs(args)
 	}
 
-	var countQuery string
-	mainQuery := strings.Join(respArray, " ")
-	if withCount { // compile query to get count of result rows
-		q := strings.TrimSpace(strings.Join([]string{"select 1", fromBlock, whereBlock}, " "))
-		countQuery = "select count(*) from (" + q + ") q"
+	res := &Result{
+		Distinct:   distinctBlock,
+		Select:     selectBlock,
+		SelectArgs: selectArgs,
+		From:       fromBlock,
+		Where:      whereBlock,
+		WhereArgs:  whereArgs,
+		GroupBy:    groupByBlock,
+		Having:     havingBlock,
+		HavingArgs: havingArgs,
+		Seek:       seekBlock,
+		SeekArgs:   seekArgs,
+		OrderBy:    rests.orderBy,
+		Limit:      rests.limit,
+		Offset:     rests.offset,
+		Args:       args,
+
+		isSeekGrouped: sel.isGrouped,
+	}
+	res.From = res.From + combineJoins(s, res.Distinct, res.Select, res.Where, res.Seek, res.GroupBy, res.Having, res.OrderBy)
+	countSelect := "1"
+	if sel.isDistinct || selectArgs != nil { // distinct rows are compared by all selected fields, arguments of select are kept
+		countSelect = selectBlock
+	}
+	if c.countMode == CountWindow { // count result rows in main query
+		if res.Distinct != "" { // window function is computed before rows deduplication
+			return nil, newError("Passed unsupported window count with distinct selection")
+		}
+		if res.Seek == "" {
+			res.Select = res.Select + ", count(*) over() as " + TotalCountColumn
+		} else { // rows before cursor are counted by subquery, which reuses placeholders of conditions
+			if d.Placeholder(1) == d.Placeholder(2) {
+				return nil, newError("Passed unsupported window count with cursor in positional placeholders")
+			}
+			res.Select = res.Select + ", (select count(*) from (" + res.assemble(countSelect, false) + ") q) as " + TotalCountColumn
+		}
+	}
+	res.MainQuery = res.SQL()
+	res.countMode, res.threshold = c.countMode, c.threshold
+	switch c.countMode {
+	case CountExact: // compile query to get count of result rows
+		res.CountQuery, res.CountArgs = "select count(*) from ("+res.assemble(countSelect, false)+") q", countArgs
+	case CountEstimated: // compile query to get estimated count of result rows
+		if c.dialect != PostgreSQL {
+			return nil, newError("Passed unsupported estimated count in " + c.dialect.Name() + " dialect")
+		}
+		res.ExactCountQuery, res.ExactCountArgs = "select count(*) from ("+res.assemble(countSelect, false)+") q", countArgs
+		if res.Where == "" && res.Distinct == "" && res.GroupBy == "" { // rows count of target, passed as the only argument
+			targetArgs := []interface{}{target}
+			countDialect := compilerDialect{Dialect: c.dialect, style: c.placeholders, start: 1}
+			res.CountQuery = "select reltuples::bigint from pg_class where oid = " + bindArgs(countDialect, 1, targetArgs) + "::regclass"
+			res.CountArgs, res.isStatistics = targetArgs, true
+		} else {
+			res.CountQuery, res.CountArgs = "explain (format json) "+res.assemble(countSelect, false), countArgs
+		}
 	}
 
-	return mainQuery, countQuery, args, nil
+	return res, nil
 }
 
-// combineSelect assembles SELECT query block
-func combineFields(fieldsMap map[string]string, fields string) (string, error) {
-	selectBlock := "select "
-
-	var preparedFields []string
+// combineFields assembles SELECT query block fields list, returns it with arguments of relation selections
+// and index of the next argument placeholder
+func combineFields(s *Schema, d Dialect, fields string, index int, withArgs bool) (string, []interface{}, int, error) {
+	var (
+		preparedFields []string
+		args           []interface{}
+	)
 	if fields == "" { // Request all model fields
-		keys := sortMap(fieldsMap)
+		keys := sortMap(s.fieldsMap)
 		for _, k := range keys {
-			preparedField := "q." + fieldsMap[k]
+			preparedField := s.selectExpr(k)
 			preparedFields = append(preparedFields, preparedField)
 		}
 	} else { // Request specific fields from query
 		fields := strings.Split(fields, ",")
 		for _, f := range fields {
-			field := fieldsMap[strings.TrimSpace(f)]
-			if field == "" {
-				return "", newError("Passed unexpected field name in select - " + f)
+			if isRelationBlock(strings.TrimSpace(f)) { // aggregate related rows into JSON array
+				preparedField, relationArgs, nextIndex, err := formRelationSelection(s, d, strings.TrimSpace(f), index, withArgs)
+				if err != nil {
+					return "", nil, 0, err
+				}
+				preparedFields, args, index = append(preparedFields, preparedField), append(args, relationArgs...), nextIndex
+				continue
+			}
+
+			preparedField := s.selectExpr(strings.TrimSpace(f))
+			if expr, alias := s.funcExpr(strings.TrimSpace(f)); expr != "" {
+				preparedField = expr + " as " + alias
+			}
+			if preparedField == "" {
+				return "", nil, 0, newError("Passed unexpected field name in select - " + f)
 			}
 
-			preparedField := "q." + field
 			preparedFields = append(preparedFields, preparedField)
 		}
 	}
-	selectBlock = selectBlock + strings.Join(preparedFields, ", ")
+	return strings.Join(preparedFields, ", "), args, index, nil
+}
+
+// parseDistinct splits distinct selection element (distinct or distinct(author|type)) off the fields block
+func parseDistinct(fields string) (isDistinct bool, on []string, rest string, err error) {
+	parts := strings.SplitN(fields, ",", 2)
+	first := strings.TrimSpace(parts[0])
+	if first != "distinct" && !strings.HasPrefix(first, "distinct(") {
+		return false, nil, fields, nil
+	}
+	if len(parts) > 1 {
+		rest = parts[1]
+	}
+	if first == "distinct" {
+		return true, nil, rest, nil
+	}
+
+	if !strings.HasSuffix(first, ")") || first == "distinct()" {
+		return false, nil, "", newError("Passed unexpected distinct selection - " + first)
+	}
+	on = strings.Split(first[len("distinct("):len(first)-1], "|")
+
+	return false, on, rest, nil
+}
+
+// combineDistinct assembles distinct clause of SELECT query block
+func combineDistinct(s *Schema, sel *selection) (string, error) {
+	if sel.isDistinct {
+		return "distinct", nil
+	}
+	if sel.distinctOn == nil {
+		return "", nil
+	}
+
+	exprs := make([]string, 0, len(sel.distinctOn))
+	for _, f := range sel.distinctOn {
+		expr := s.fieldExpr(strings.TrimSpace(f))
+		if expr == "" {
+			return "", newError("Passed unexpected field name in distinct - " + f)
+		}
+		exprs = append(exprs, expr)
+	}
 
-	return selectBlock, nil
+	return "distinct on (" + strings.Join(exprs, ", ") + ")", nil
 }
 
-// combineTarget assembles FROM query block
+// joinSeekCondition joins keyset predicate of cursor to conditions expression
+func joinSeekCondition(conds, seekCond string) string {
+	if conds == "" {
+		return seekCond
+	}
+
+	return "(" + conds + ") and " + seekCond
+}
+
+// combineTarget assembles FROM query block target
 func combineTarget(target string) (string, error) {
 	if target == "" {
 		return "", newError("Request target not passed")
 	}
 
-	return "from " + target + " q", nil
+	return target + " q", nil
 }
 
-// combineConditions assembles WHERE query block
-func combineConditions(fieldsMap map[string]string, conds, searchParams string, withArgs bool) (string, []interface{}, error) {
+// combineConditions assembles WHERE query block expression, returns it with arguments
+// and index of the next argument placeholder
+func combineConditions(s *Schema, d Dialect, conds, searchParams string, scopes []Scope, withArgs bool) (string, []interface{}, int, error) {
 	// Prune spaces
 	conds = strings.ReplaceAll(conds, " ", "")
 	searchParams = strings.ReplaceAll(searchParams, " ", "%")
 
+	scopeConds, scopeArgs, err := formScopeConditions(d, scopes)
+	if err != nil {
+		return "", nil, 0, err
+	}
+
 	if conds == "" && searchParams == "" {
-		return "", nil, nil
+		if scopeConds != "" {
+			return scopeConds, scopeArgs, len(scopeArgs) + 1, nil
+		}
+		return "", nil, 1, nil
 	}
 
 	var condIndex *int
 	if withArgs {
-		condIndex = func(i int)*int{return &i}(1)
+		condIndex = func(i int)*int{return &i}(len(scopeArgs) + 1)
 	}
 
-	whereBlock := "where "
+	clientBlock := ""
 	var searchArgs []interface{}
 	if searchParams != "" { // searchQuery handling
-		var (
-			searchConds string
-			err error
-		)
-		searchConds, searchArgs, condIndex, err = formSearchConditions(fieldsMap, searchParams, condIndex)
+		var searchConds string
+		searchConds, searchArgs, condIndex, err = formSearchConditions(s, d, searchParams, condIndex)
 		if err != nil {
-			return "", nil, err
+			return "", nil, 0, err
 		}
 		if searchConds != "" && conds != "" {
-			whereBlock = whereBlock + searchConds + "and "
+			clientBlock = clientBlock + searchConds + "and "
 		} else if searchConds != "" {
-			whereBlock = whereBlock + searchConds
+			clientBlock = clientBlock + searchConds
 		}
 	}
 
 	// standart conditions block handling
-	preparedConditions, preparedArgs, _, err := extractConditionsSet(fieldsMap, conds, false, condIndex)
+	preparedConditions, preparedArgs, condIndex, err := extractConditionsSet(s, d, conds, false, condIndex)
 	if err != nil {
-		return "", nil, err
+		return "", nil, 0, err
 	}
+	clientBlock = clientBlock + strings.Join(preparedConditions, " ")
+	clientArgs := append(searchArgs, preparedArgs...)
 
-	return whereBlock + strings.Join(preparedConditions, " "), append(searchArgs, preparedArgs...), nil
+	nextIndex := len(scopeArgs) + 1
+	if condIndex != nil {
+		nextIndex = *condIndex
+	}
+
+	if scopeConds != "" { // isolate client expression from scopes
+		if !withArgs { // client values are inlined into query
+			clientArgs = nil
+		}
+		return scopeConds + " and (" + strings.TrimSpace(clientBlock) + ")", append(scopeArgs, clientArgs...), nextIndex, nil
+	}
+
+	return strings.TrimSpace(clientBlock), clientArgs, nextIndex, nil
 }
 
-// combineRestrictions assembles selection parameters
-func combineRestrictions(fieldsMap map[string]string, rests string) (string, error) {
-	if rests == "" {
-		return "", nil
+// formScopeConditions builds mandatory scope predicates joined with AND operator
+func formScopeConditions(d Dialect, scopes []Scope) (string, []interface{}, error) {
+	var (
+		preparedConds []string
+		preparedArgs  []interface{}
+	)
+	for _, scope := range scopes {
+		if !columnNameRegexp.MatchString(scope.Column) {
+			return "", nil, newError("Passed unexpected column name in scope - " + scope.Column)
+		}
+		field := "q." + scope.Column
+
+		if scope.Value == nil { // null values
+			switch nullOperatorBindings[scope.Operator] {
+			case "=":
+				preparedConds = append(preparedConds, field+" is null")
+			case "!=":
+				preparedConds = append(preparedConds, field+" is not null")
+			default:
+				return "", nil, newError("Passed unexpected operator in NULL scope - " + scope.Operator)
+			}
+			continue
+		}
+
+		kind := reflect.ValueOf(scope.Value).Kind()
+		if kind == reflect.Slice || kind == reflect.Array { // array values
+			arrArgs := arrayArgs(d, scope.Value)
+			placeholder := bindArgs(d, len(preparedArgs)+1, arrArgs)
+			preparedArgs = append(preparedArgs, arrArgs...)
+			switch operatorBindings[scope.Operator] {
+			case "=", "!=":
+				preparedConds = append(preparedConds, d.InArray(field, placeholder, scope.Operator == "!="))
+			case "&&", "!&&":
+				cond, err := d.Overlaps(field, placeholder, scope.Operator == "!!")
+				if err != nil {
+					return "", nil, err
+				}
+				preparedConds = append(preparedConds, cond)
+			case "->>":
+				cond, err := d.Includes(field, placeholder)
+				if err != nil {
+					return "", nil, err
+				}
+				preparedConds = append(preparedConds, cond)
+			default:
+				return "", nil, newError("Passed unexpected operator in array scope - " + scope.Operator)
+			}
+			continue
+		}
+
+		scopeArgs := []interface{}{scope.Value}
+		placeholder := bindArgs(d, len(preparedArgs)+1, scopeArgs)
+
+		switch operatorBindings[scope.Operator] {
+		case "=", "!=", "<", "<=", ">", ">=":
+			preparedArgs = append(preparedArgs, scopeArgs...)
+			preparedConds = append(preparedConds, field+" "+operatorBindings[scope.Operator]+" "+placeholder)
+		default:
+			return "", nil, newError("Passed unexpected operator in scope - " + scope.Operator)
+		}
+	}
+
+	return strings.Join(preparedConds, " and "), preparedArgs, nil
+}
+
+// restrictions describes selection parameters of restrictions block
+type restrictions struct {
+	orderBy string
+	fields  []string // sort fields names
+	exprs   []string // sort fields sql expressions
+	orders  []string // selection order of sort fields
+	limit   *int
+	offset  *int
+	cursor  *cursor // position of keyset selection
+}
+
+// combineRestrictions assembles selection parameters - ORDER BY expression, limit, offset and cursor
+func combineRestrictions(s *Schema, d Dialect, rests string, sel *selection, limits PageLimits) (*restrictions, error) {
+	if rests == "" { // imitate block structure to apply page limits
+		rests = ",,,"
 	}
 	restsArr := strings.Split(rests, ",")
-	restsBlock := ""
+	r := &restrictions{}
 
 	// order
 	order := restsArr[1]
 	if order != "" {
 		if order != "asc" && order != "desc" {
-			return "", newError("Unexpected selection order - " + order)
+			return nil, newError("Unexpected selection order - " + order)
 		}
 	} else {
 		order = "asc"
 	}
 
+	// limit
+	if l := restsArr[2]; l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil {
+			return nil, newError("Unexpected selection limit - " + l)
+		}
+		if n < 0 {
+			return nil, newError("Invaild negative selection limit - " + l)
+		}
+		r.limit = &n
+	}
+	r.limit = applyPageLimit(r.limit, limits)
+
+	// offset
+	if o := restsArr[3]; o != "" {
+		n, err := strconv.Atoi(o)
+		if err != nil {
+			return nil, newError("Unexpected selection offset - " + o)
+		}
+		if n < 0 {
+			return nil, newError("Invaild negative selection offset - " + o)
+		}
+		if err := checkPageOffset(n, limits); err != nil {
+			return nil, err
+		}
+		r.offset = &n
+	}
+
+	cursorToken := ""
+	if len(restsArr) > 4 {
+		cursorToken = restsArr[4]
+	}
+
 	// fields
-	if restsArr[0] != "" {
-		for i, field := range strings.Split(restsArr[0], "|") {
-			f := fieldsMap[field]
-			if f == "" {
-				return "", newError("Unexpected selection order field - " + restsArr[0])
+	tokens, err := s.sortTokens(restsArr[0], order, sel, r.limit != nil || r.offset != nil || cursorToken != "")
+	if err != nil {
+		return nil, err
+	}
+	for i, token := range tokens {
+		field, fieldOrder, nulls, err := parseSortToken(token, order)
+		if err != nil {
+			return nil, err
+		}
+		f := s.valueExpr(field)
+		if f == "" {
+			return nil, newError("Unexpected selection order field - " + restsArr[0])
+		}
+		r.fields = append(r.fields, field)
+		r.exprs = append(r.exprs, f)
+		r.orders = append(r.orders, fieldOrder)
+
+		if i == 0 {
+			r.orderBy = d.SortExpr(f, fieldOrder, nulls)
+		} else {
+			r.orderBy = r.orderBy + ", " + d.SortExpr(f, fieldOrder, nulls)
+		}
+	}
+
+	// cursor
+	if cursorToken != "" {
+		if r.offset != nil {
+			return nil, newError("Passed selection offset with cursor")
+		}
+
+		c, err := decodeCursor(cursorToken, r.fields)
+		if err != nil {
+			return nil, err
+		}
+		r.cursor = c
+	}
+
+	return r, nil
+}
+
+// sortTokens returns sort fields tokens of query. Default sort of schema is used if query does not contain sort fields
+// and selection is not grouped, distinct on fields are moved to the beginning of sort fields, as PostgreSQL requires,
+// primary key or group fields are appended to sort fields of paginated query as a unique tiebreaker
+func (s *Schema) sortTokens(fields, order string, sel *selection, isPaginated bool) ([]string, error) {
+	isDefault := false
+	if fields == "" && !sel.isGrouped {
+		fields, isDefault = s.config.defaultSort, true
+	}
+	var tokens []string
+	if fields != "" {
+		tokens = strings.Split(fields, "|")
+	}
+	isPlainDistinct := sel.isDistinct && sel.distinctOn == nil
+	if isPlainDistinct { // distinct rows are sorted by selected fields only
+		var err error
+		tokens, err = selectedSortTokens(tokens, sel, order, isDefault)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if sel.distinctOn != nil {
+		var err error
+		tokens, err = distinctSortTokens(tokens, sel.distinctOn, order)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if sel.isGrouped { // groups are sorted by group fields and aggregate functions only
+		for _, token := range tokens {
+			field, _, _, err := parseSortToken(token, order)
+			if err != nil {
+				return nil, err
 			}
+			if !sel.isGroupedField(field) {
+				return nil, newError("Passed ungrouped field in order - " + field)
+			}
+		}
+	}
 
-			if i == 0 {
-				restsBlock = "order by q." + f + " " + order
-			} else {
-				restsBlock = restsBlock + ", q." + f + " " + order
+	keys := s.pk
+	if sel.isGrouped { // groups are unique by group fields
+		keys = sel.groupBy
+	}
+	if isPlainDistinct { // not selected keys can not be sorted
+		var selected []string
+		for _, f := range keys {
+			if sel.isSelected(f) {
+				selected = append(selected, f)
 			}
 		}
+		keys = selected
+	}
+	if !isPaginated || len(keys) == 0 {
+		return tokens, nil
 	}
 
-	// limit
-	limit := restsArr[2]
-	if limit != "" {
-		n, err := strconv.Atoi(limit)
+	lastOrder := order // tiebreaker follows order of the last sort field
+	sorted := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		field, fieldOrder, _, err := parseSortToken(token, order)
 		if err != nil {
-			return "", newError("Unexpected selection limit - " + limit)
+			return nil, err
 		}
-		if n < 0 {
-			return "", newError("Invaild negative selection limit - " + limit)
+		sorted[field] = true
+		lastOrder = fieldOrder
+	}
+	for _, f := range keys {
+		if !sorted[f] {
+			tokens = append(tokens, f+":"+lastOrder)
 		}
+	}
 
-		if restsBlock == "" {
-			restsBlock = "limit " + limit
-		} else {
-			restsBlock = restsBlock + " limit " + limit
+	return tokens, nil
+}
+
+// selectedSortTokens checks that sort fields of distinct selection are selected, not selected fields of default sort are skipped
+func selectedSortTokens(tokens []string, sel *selection, order string, isDefault bool) ([]string, error) {
+	var result []string
+	for _, token := range tokens {
+		field, _, _, err := parseSortToken(token, order)
+		if err != nil {
+			return nil, err
+		}
+		if sel.isSelected(field) {
+			result = append(result, token)
+		} else if !isDefault {
+			return nil, newError("Passed not selected order field in distinct selection - " + field)
 		}
 	}
 
-	// offset
-	offset := restsArr[3]
-	if offset != "" {
-		n, err := strconv.Atoi(offset)
+	return result, nil
+}
+
+// distinctSortTokens reorders sort fields tokens to begin with distinct on fields,
+// distinct on fields missing in sort fields are sorted in selection order of restrictions block
+func distinctSortTokens(tokens, distinctOn []string, order string) ([]string, error) {
+	byField := make(map[string]string, len(tokens))
+	for _, token := range tokens {
+		field, _, _, err := parseSortToken(token, order)
 		if err != nil {
-			return "", newError("Unexpected selection offset - " + offset)
-		}
-		if n < 0 {
-			return "", newError("Invaild negative selection offset - " + offset)
+			return nil, err
 		}
+		byField[field] = token
+	}
 
-		if restsBlock == "" {
-			restsBlock = "offset " + offset
+	result := make([]string, 0, len(tokens)+len(distinctOn))
+	isLeading := make(map[string]bool, len(distinctOn))
+	for _, f := range distinctOn {
+		f = strings.TrimSpace(f)
+		if isLeading[f] {
+			continue
+		}
+		isLeading[f] = true
+		if token, ok := byField[f]; ok {
+			result = append(result, token)
 		} else {
-			restsBlock = restsBlock + " offset " + offset
+			result = append(result, f)
+		}
+	}
+	for _, token := range tokens {
+		field, _, _, _ := parseSortToken(token, order)
+		if !isLeading[field] {
+			result = append(result, token)
 		}
 	}
 
-	return restsBlock, nil
+	return result, nil
+}
+
+// parseSortToken splits sort field token (createdAt:desc:nullslast) into field name, selection order
+// and nulls placement. Selection order of restrictions block is used if token does not contain one.
+// Field function (count:sum:desc) is kept in field name
+func parseSortToken(token, defaultOrder string) (field, order, nulls string, err error) {
+	parts := strings.Split(token, ":")
+	field, order = parts[0], defaultOrder
+	for i, p := range parts[1:] {
+		switch {
+		case p == "asc" || p == "desc":
+			order = p
+		case p == "nullsfirst":
+			nulls = "first"
+		case p == "nullslast":
+			nulls = "last"
+		case i == 0 && isFieldFunc(p):
+			field = field + ":" + p
+		default:
+			return "", "", "", newError("Unexpected selection order - " + p)
+		}
+	}
+
+	return field, order, nulls, nil
 }
 
 // formSearchConditions builds a conditions block with LIKE operator for search
-func formSearchConditions(fieldsMap map[string]string, params string, condIndex *int) (string, []interface{}, *int, error) {
-	preparedConds, preparedArgs, condI, err := extractConditionsSet(fieldsMap, params, true, condIndex)
+func formSearchConditions(s fieldResolver, d Dialect, params string, condIndex *int) (string, []interface{}, *int, error) {
+	preparedConds, preparedArgs, condI, err := extractConditionsSet(s, d, params, true, condIndex)
 	if err != nil {
 		return "", nil, nil, err
 	}
@@ -255,16 +789,16 @@ func formSearchConditions(fieldsMap map[string]string, params string, condIndex
 	return "(" + strings.Join(preparedConds, " ") + ") ", preparedArgs, condI, nil
 }
 
-func extractConditionsSet(fieldsMap map[string]string, conds string, isSearch bool, condIndex *int) ([]string, []interface{}, *int, error) {
+func extractConditionsSet(s fieldResolver, d Dialect, conds string, isSearch bool, condIndex *int) ([]string, []interface{}, *int, error) {
 	if isSearch {
 		conds = strings.ReplaceAll(conds, "(", "")
 		conds = strings.ReplaceAll(conds, ")", "")
 	}
-	bracketSubstrings := regexp.MustCompile(`\(.*?(=|~|\|).*?\)`).FindAllString(conds, -1) // \((.*?)\)
+	bracketSubstrings := bracketConditionsRegexp.FindAllString(conds, -1)
 
 	// Parse logical operators
 	var (
-		arg interface{}
+		args []interface{}
 		preparedArgs []interface{}
 		preparedConds []string
 	)
@@ -279,12 +813,12 @@ func extractConditionsSet(fieldsMap map[string]string, conds string, isSearch bo
 		)
 		opCount := strings.Count(condSet, "*") + strings.Count(condSet, "||")
 		for i := 0; i <= opCount; i++ { // loop number of logical operators in condition set
-			condSet, cond, arg, condIndex, err = handleConditionsSet(fieldsMap, condSet, isSearch, condIndex)
+			condSet, cond, args, condIndex, err = handleConditionsSet(s, d, condSet, isSearch, condIndex)
 			if err != nil {
 				return nil, nil, nil, err
 			}
 			bracketConditions = append(bracketConditions, cond)
-			preparedArgs = append(preparedArgs, arg)
+			preparedArgs = append(preparedArgs, args...)
 		}
 		conds = strings.TrimPrefix(conds, brCondSet)
 
@@ -315,58 +849,58 @@ func extractConditionsSet(fieldsMap map[string]string, conds string, isSearch bo
 	opCount := strings.Count(conds, "*") + strings.Count(conds, "||")
 	if conds != "" { // handle non-bracket conditions set
 		for i := 0; i <= opCount; i++ { // loop number of logical operators in condition set
-			conds, cond, arg, condIndex, err = handleConditionsSet(fieldsMap, conds, isSearch, condIndex)
+			conds, cond, args, condIndex, err = handleConditionsSet(s, d, conds, isSearch, condIndex)
 			if err != nil {
 				return nil, nil, nil, err
 			}
 			preparedConds = append(preparedConds, cond)
-			preparedArgs = append(preparedArgs, arg)
+			preparedArgs = append(preparedArgs, args...)
 		}
 	}
 
 	return preparedConds, preparedArgs, condIndex, nil
 }
 
-func handleConditionsSet(fieldsMap map[string]string, condSet string, isSearch bool, condIndex *int) (string, string, interface{}, *int, error) {
+func handleConditionsSet(s fieldResolver, d Dialect, condSet string, isSearch bool, condIndex *int) (string, string, []interface{}, *int, error) {
 	orIndex := strings.Index(condSet, "||")
 	andIndex := strings.Index(condSet, "*")
 
 	var (
 		err error
 		cond string
-		arg interface{}
+		args []interface{}
 	)
 	if orIndex < 0 && andIndex < 0 { // no logical condition
-		cond, arg, condIndex, err = formCondition(fieldsMap, condSet, "", isSearch, condIndex)
+		cond, args, condIndex, err = formCondition(s, d, condSet, "", isSearch, condIndex)
 		if err != nil {
 			return "", "", nil, nil, err
 		}
 	} else if orIndex < 0 || (andIndex < orIndex && andIndex >= 0) { // handle AND logical condition
-		cond, arg, condIndex, err = formCondition(fieldsMap, condSet[:strings.Index(condSet, "*")], "*", isSearch, condIndex)
+		cond, args, condIndex, err = formCondition(s, d, condSet[:strings.Index(condSet, "*")], "*", isSearch, condIndex)
 		if err != nil {
 			return "", "", nil, nil, err
 		}
 		condSet = strings.TrimPrefix(condSet, condSet[:strings.Index(condSet, "*")]+"*")
 	} else { // handle OR logical condition
-		cond, arg, condIndex, err = formCondition(fieldsMap, condSet[:strings.Index(condSet, "||")], "||", isSearch, condIndex)
+		cond, args, condIndex, err = formCondition(s, d, condSet[:strings.Index(condSet, "||")], "||", isSearch, condIndex)
 		if err != nil {
 			return "", "", nil, nil, err
 		}
 		condSet = strings.TrimPrefix(condSet, condSet[:strings.Index(condSet, "||")]+"||")
 	}
 
-	return condSet, cond, arg, condIndex, nil
+	return condSet, cond, args, condIndex, nil
 }
 
 // formCondition builds condition with standart operator
-func formCondition(fieldsMap map[string]string, cond, logicalOperator string, isSearch bool, condIndex *int) (cnd string, ar interface{}, ind *int, er error) {
+func formCondition(s fieldResolver, d Dialect, cond, logicalOperator string, isSearch bool, condIndex *int) (cnd string, ar []interface{}, ind *int, er error) {
 	var arg interface{}
 	if isSearch { // handle search condition
 		condParts := strings.Split(cond, "~~")
 		if condParts == nil {
 			return "", nil, nil, nil
 		}
-		f := fieldsMap[condParts[0]]
+		f := s.fieldExpr(condParts[0])
 		if f == "" {
 			return "", nil, nil, newError("Passed unexpected field name in search condition - " + condParts[0])
 		}
@@ -374,13 +908,12 @@ func formCondition(fieldsMap map[string]string, cond, logicalOperator string, is
 		// handle nested JSONB search field
 		nestedArr := strings.Split(condParts[1], "^^")
 		if nestedArr[0] != condParts[1] {
-			f = "lower(q." + f + operatorBindings["->>"] + `'` + nestedArr[0] + `'::text) like `
+			f = d.JSONField(f, nestedArr[0])
 			condParts[1] = nestedArr[1]
-		} else {
-			f = "lower(q." + f + `::text) like `
 		}
 
 		value := "%" + pruneInjections(condParts[1], true) + "%"
+		args := []interface{}{nil}
 		if condIndex != nil {
 			v, err := strconv.Atoi(value)
 			if err == nil {
@@ -396,19 +929,28 @@ func formCondition(fieldsMap map[string]string, cond, logicalOperator string, is
 				arg = strings.ToLower(value)
 			}
 
-			value = "$" + strconv.Itoa(*condIndex)
+			args = []interface{}{arg}
+			value = bindArgs(d, *condIndex, args)
 			condIndex = func(i int)*int{i = *condIndex + 1; return &i}(*condIndex)
 		}
 
 		if logicalOperator != "" {
-			return f + strings.ToLower(value) + " " + logicalBindings[logicalOperator], arg, condIndex, nil
+			return d.Like(f, strings.ToLower(value)) + " " + logicalBindings[logicalOperator], args, condIndex, nil
 		}
 
-		return f + strings.ToLower(value), arg, condIndex, nil
+		return d.Like(f, strings.ToLower(value)), args, condIndex, nil
+	}
+
+	if isRelationBlock(cond) { // handle relation condition
+		return formExistsCondition(s, d, cond, logicalOperator, condIndex)
 	}
 
 	var sep string
 	for queryOp := range operatorBindings { // check is condition legal
+		if strings.Contains(cond, "->>") { // INCLUDES contains characters of other operators
+			sep = "->>"
+			break
+		}
 		if strings.Contains(cond, queryOp) {
 			sep = queryOp
 		}
@@ -432,7 +974,7 @@ func formCondition(fieldsMap map[string]string, cond, logicalOperator string, is
 	f := strings.Split(cond, sep)[0]
 	value := pruneInjections(strings.Split(cond, sep)[1], false)
 
-	field := fieldsMap[f]
+	field := s.fieldExpr(f)
 	if field == "" {
 		return "", nil, nil, newError("Passed unexpected field name in condition - " + f)
 	}
@@ -441,8 +983,8 @@ func formCondition(fieldsMap map[string]string, cond, logicalOperator string, is
 	var valueType string
 	nestedArr := strings.Split(value, "^^")
 	if nestedArr[0] != value {
-		field = "q." + f + operatorBindings["->>"] + `'` + nestedArr[0] + `'`
-		if strings.Contains(nestedArr[1], ",") || sep == ">>" { // handle nested JSONB array value
+		field = d.JSONField(field, nestedArr[0])
+		if strings.Contains(nestedArr[1], ",") || sep == ">>" || sep == "->>" { // handle nested JSONB array value
 			value = handleArrCondValues(nestedArr[1], false)
 			valueType = "ARRAY"
 		}
@@ -463,8 +1005,6 @@ func formCondition(fieldsMap map[string]string, cond, logicalOperator string, is
 			value = nestedArr[1]
 			valueType = "STRING"
 		}
-	} else {
-		field = "q." + field
 	}
 
 	// Handle value type
@@ -475,11 +1015,11 @@ func formCondition(fieldsMap map[string]string, cond, logicalOperator string, is
 		valueType = "BOOL"
 	default:
 		_, err := strconv.Atoi(value) // INTEGER
-		if err == nil && sep != ">>" {
+		if err == nil && sep != ">>" && sep != "->>" {
 			valueType = "INT"
 		}
 
-		if valueType == "" && (strings.Contains(value, ",") || sep == ">>") { // ARRAY
+		if valueType == "" && (strings.Contains(value, ",") || sep == ">>" || sep == "->>") { // ARRAY
 			value = handleArrCondValues(value, false)
 			valueType = "ARRAY"
 		}
@@ -492,37 +1032,45 @@ func formCondition(fieldsMap map[string]string, cond, logicalOperator string, is
 	value = strings.TrimRight(value, ",")
 
 	// handle separate query+args implementation
-	if condIndex != nil && valueType != "NULL" {
-		arg, value, condIndex = handleArgValue(value, valueType, condIndex)
+	args := []interface{}{nil}
+	if condIndex != nil && valueType == "NULL" && !isNullArgs(d) { // null values are not bound to placeholders
+		args = nil
+	} else if condIndex != nil && valueType != "NULL" {
+		args, value, condIndex = handleArgValue(d, value, valueType, condIndex)
+	} else if valueType == "ARRAY" {
+		value = d.ArrayValues(value)
 	}
 
 	switch operatorBindings[sep] { // switch operators
-	case "&&": // handle OVERLAPS operator
-		switch valueType {
-		case "ARRAY": // array format
-			cond = field + " " + operatorBindings[sep] + " " + value
-		case "NULL": // unexpected null value
+	case "&&", "!&&": // handle OVERLAPS and NOT OVERLAPS operators
+		if valueType == "NULL" { // unexpected null value
+			if sep == "!!" {
+				return "", nil, nil, newError("Passed unexpected NOT OVERLAPS operator in NULL condition")
+			}
 			return "", nil, nil, newError("Passed unexpected OVERLAPS operator in NULL condition")
-		default: // others
-			cond = field + " " + operatorBindings[sep] + " " + value
 		}
-	case "!&&": // handle NOT OVERLAPS operator
-		switch valueType {
-		case "ARRAY": // array format
-			cond = "not " + field + " && " + value
-		case "NULL": // unexpected null value
-			return "", nil, nil, newError("Passed unexpected NOT OVERLAPS operator in NULL condition")
-		default: // others
-			cond = "not " + field + " && " + value
+
+		var err error
+		cond, err = d.Overlaps(field, value, sep == "!!")
+		if err != nil {
+			return "", nil, nil, err
+		}
+	case "->>": // handle INCLUDES operator
+		if valueType == "NULL" { // unexpected null value
+			return "", nil, nil, newError("Passed unexpected INCLUDES operator in NULL condition")
+		}
+
+		var err error
+		cond, err = d.Includes(field, value)
+		if err != nil {
+			return "", nil, nil, err
 		}
 	default: // rest of operators
 		switch valueType {
 		case "ARRAY": // array format
 			switch operatorBindings[sep] { // handle operators inside array condition
-			case "=":
-				cond = field + " =" + " any(" + value + ")"
-			case "!=":
-				cond = "not " + field + " =" + " any(" + value + ")"
+			case "=", "!=":
+				cond = d.InArray(field, value, sep == "!=")
 			default:
 				return "", nil, nil, newError("Passed unexpected operator in array condition - " + sep)
 			}
@@ -541,14 +1089,14 @@ func formCondition(fieldsMap map[string]string, cond, logicalOperator string, is
 	}
 
 	if logicalOperator != "" {
-		return cond + " " + logicalBindings[logicalOperator], arg, condIndex, nil
+		return cond + " " + logicalBindings[logicalOperator], args, condIndex, nil
 	}
 
-	return cond, arg, condIndex, nil
+	return cond, args, condIndex, nil
 }
 
 // handleArgValue ...
-func handleArgValue(value, valueType string, condIndex *int) (ar interface{}, val string, ind *int) {
+func handleArgValue(d Dialect, value, valueType string, condIndex *int) (ar []interface{}, val string, ind *int) {
 	var arg interface{}
 	switch valueType {
 	case "ARRAY":
@@ -561,10 +1109,14 @@ func handleArgValue(value, valueType string, condIndex *int) (ar interface{}, va
 				v, _ := strconv.Atoi(el)
 				intArr = append(intArr, v)
 			}
-			arg = pq.Array(intArr)
+			arg = intArr
 		} else {
-			arg = pq.Array(arrValues)
+			arg = arrValues
 		}
+
+		args := arrayArgs(d, arg)
+		value = bindArgs(d, *condIndex, args)
+		return args, value, func(i int)*int{i = *condIndex + len(args); return &i}(*condIndex)
 	case "INT":
 		v, _ := strconv.Atoi(value)
 		arg = v
@@ -575,8 +1127,9 @@ func handleArgValue(value, valueType string, condIndex *int) (ar interface{}, va
 		arg = value
 	}
 
-	value = "$" + strconv.Itoa(*condIndex)
-	return arg, value, func(i int)*int{i = *condIndex + 1; return &i}(*condIndex)
+	args := []interface{}{arg}
+	value = bindArgs(d, *condIndex, args)
+	return args, value, func(i int)*int{i = *condIndex + 1; return &i}(*condIndex)
 }
 
 // handleArrCondValues preprocess values inside query condition
@@ -608,7 +1161,7 @@ func handleArrCondValues(value string, isNestedJson bool) string {
 // pruneInjections cleans query params from SQL marks
 func pruneInjections(str string, isSearch bool) string {
 	if isSearch {
-		return regexp.MustCompile(`[^а-яА-Яa-zA-Z0-9',№^ ]+`).ReplaceAllString(str, "%")
+		return searchInjectionsRegexp.ReplaceAllString(str, "%")
 	}
-	return regexp.MustCompile(`[^а-яА-Яa-zA-Z0-9{}_',-^ ]+`).ReplaceAllString(str, "")
+	return injectionsRegexp.ReplaceAllString(str, "")
 }
@@ -1,7 +1,9 @@
 package compiler
 
 import (
+	"context"
 	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -531,6 +533,79 @@ var testGetCases = []struct {
 		CountQuery: "select count(*) from (select 1 from v_test q where not q.id && 1) q",
 		Err:        newError(""),
 	},
+	{ // 51. Test restrictions params block with per-field order
+		Target:    "v_test",
+		Params:    "ID??count:desc|ID:asc,,10,",
+		WithCount: false,
+		WithArgs:  false,
+
+		MainQuery:  "select q.id from v_test q order by q.count desc, q.id asc limit 10",
+		Err:        newError(""),
+	},
+	{ // 52. Test restrictions params block with nulls placement and block order
+		Target:    "v_test",
+		Params:    "ID??content:nullslast|count:asc:nullsfirst|ID,desc,,",
+		WithCount: false,
+		WithArgs:  false,
+
+		MainQuery:  "select q.id from v_test q order by q.content desc nulls last, q.count asc nulls first, q.id desc",
+		Err:        newError(""),
+	},
+	{ // 53. Test ERROR restrictions params block with unexpected field order
+		Target:    "v_test",
+		Params:    "ID??ID:up,,,",
+		WithCount: false,
+		WithArgs:  false,
+
+		Err:        newError("Unexpected selection order - up"),
+	},
+	{ // 54. Test distinct selection
+		Target:    "v_test",
+		Params:    "distinct,content,count?ID>1?content,asc,10,",
+		WithCount: true,
+		WithArgs:  true,
+
+		MainQuery:  "select distinct q.content, q.count from v_test q where q.id > $1 order by q.content asc limit 10",
+		CountQuery: "select count(*) from (select distinct q.content, q.count from v_test q where q.id > $1) q",
+		Args:       []interface{}{1},
+		Err:        newError(""),
+	},
+	{ // 55. Test distinct on selection with distinct fields moved to the beginning of order
+		Target:    "v_test",
+		Params:    "distinct(content|count),ID,content?ID>1?ID:desc|count:desc,,,",
+		WithCount: true,
+		WithArgs:  true,
+
+		MainQuery:  "select distinct on (q.content, q.count) q.id, q.content from v_test q where q.id > $1 order by q.content asc, q.count desc, q.id desc",
+		CountQuery: "select count(*) from (select distinct on (q.content, q.count) 1 from v_test q where q.id > $1) q",
+		Args:       []interface{}{1},
+		Err:        newError(""),
+	},
+	{ // 56. Test distinct on selection of all fields without order
+		Target:    "v_test",
+		Params:    "distinct(content)??",
+		WithCount: false,
+		WithArgs:  false,
+
+		MainQuery:  "select distinct on (q.content) q.id, q.content, q.count, q.extra_field, q.is_bool, q.one_more_field from v_test q order by q.content asc",
+		Err:        newError(""),
+	},
+	{ // 57. Test ERROR distinct on selection with unexpected field
+		Target:    "v_test",
+		Params:    "distinct(title),ID??",
+		WithCount: false,
+		WithArgs:  false,
+
+		Err:        newError("Passed unexpected field name in distinct - title"),
+	},
+	{ // 58. Test ERROR distinct on selection without fields
+		Target:    "v_test",
+		Params:    "distinct(),ID??",
+		WithCount: false,
+		WithArgs:  false,
+
+		Err:        newError("Passed unexpected distinct selection - distinct()"),
+	},
 }
 
 func TestGet(t *testing.T) {
@@ -566,6 +641,297 @@ func TestGet(t *testing.T) {
 	}
 }
 
+var testScopedCases = []struct {
+	// Scoped params
+	Target       string
+	Params       string
+	WithCount    bool
+	WithArgs     bool
+	SearchParams string
+	Scopes       []Scope
+
+	// Scoped response
+	MainQuery  string
+	CountQuery string
+	Args       []interface{}
+	Err        error
+}{
+	{ // 1. Test scope with client OR conditions set (withArgs)
+		Target:    "v_test",
+		Params:    "ID?ID==1||ID==2?",
+		WithCount: true,
+		WithArgs:  true,
+		Scopes:    []Scope{{Column: "tenant_id", Operator: "==", Value: 5}},
+
+		MainQuery:  "select q.id from v_test q where q.tenant_id = $1 and (q.id = $2 or q.id = $3)",
+		CountQuery: "select count(*) from (select 1 from v_test q where q.tenant_id = $1 and (q.id = $2 or q.id = $3)) q",
+		Args:       []interface{}{5, 1, 2},
+		Err:        newError(""),
+	},
+	{ // 2. Test scope without client conditions
+		Target:    "v_test",
+		Params:    "ID??ID,desc,10,",
+		WithCount: true,
+		WithArgs:  true,
+		Scopes:    []Scope{{Column: "tenant_id", Operator: "==", Value: 5}},
+
+		MainQuery:  "select q.id from v_test q where q.tenant_id = $1 order by q.id desc limit 10",
+		CountQuery: "select count(*) from (select 1 from v_test q where q.tenant_id = $1) q",
+		Args:       []interface{}{5},
+		Err:        newError(""),
+	},
+	{ // 3. Test scope with inline client conditions
+		Target:    "v_test",
+		Params:    "ID?(ID==1||isBool==true)*count>2?",
+		WithCount: false,
+		WithArgs:  false,
+		Scopes:    []Scope{{Column: "tenant_id", Operator: "==", Value: 5}},
+
+		MainQuery: "select q.id from v_test q where q.tenant_id = $1 and ((q.id = 1 or q.is_bool = true) and q.count > 2)",
+		Args:      []interface{}{5},
+		Err:       newError(""),
+	},
+	{ // 4. Test multiple scopes with NULL and array values
+		Target:    "v_test",
+		Params:    "ID?count>2?",
+		WithCount: false,
+		WithArgs:  true,
+		Scopes: []Scope{
+			{Column: "tenant_id", Operator: "==", Value: []int{5, 6}},
+			{Column: "deleted_at", Operator: "==", Value: nil},
+			{Column: "level", Operator: "<=", Value: 3},
+		},
+
+		MainQuery: "select q.id from v_test q where q.tenant_id = any($1) and q.deleted_at is null and q.level <= $2 and (q.count > $3)",
+		Args:      []interface{}{[]int{5, 6}, 3, 2},
+		Err:       newError(""),
+	},
+	{ // 5. Test scope with search conditions
+		Target:       "v_test",
+		Params:       "ID?ID!=1?",
+		WithCount:    false,
+		WithArgs:     true,
+		SearchParams: "content~~smth||content~~anth",
+		Scopes:       []Scope{{Column: "tenant_id", Operator: "==", Value: 5}},
+
+		MainQuery: "select q.id from v_test q where q.tenant_id = $1 and ((lower(q.content::text) like $2 or lower(q.content::text) like $3) and q.id != $4)",
+		Args:      []interface{}{5, "%smth%", "%anth%", 1},
+		Err:       newError(""),
+	},
+	{ // 6. Test ERROR unexpected scope column
+		Target:   "v_test",
+		Params:   "ID??",
+		WithArgs: true,
+		Scopes:   []Scope{{Column: "tenant_id=1 or 1", Operator: "==", Value: 5}},
+
+		Err: newError("Passed unexpected column name in scope - tenant_id=1 or 1"),
+	},
+	{ // 7. Test ERROR unexpected scope operator
+		Target:   "v_test",
+		Params:   "ID??",
+		WithArgs: true,
+		Scopes:   []Scope{{Column: "tenant_id", Operator: "->>", Value: 5}},
+
+		Err: newError("Passed unexpected operator in scope - ->>"),
+	},
+}
+
+func TestScoped(t *testing.T) {
+	for index, c := range testScopedCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			mainQuery, countQuery, args, err := SearchScoped(TestModel{}, c.Target, c.Params, c.WithCount, c.WithArgs, c.SearchParams, c.Scopes)
+			if err != nil && err.Error() != c.Err.Error() {
+				t.Errorf("expected err: %v, got: %v", c.Err, err)
+				t.FailNow()
+			}
+
+			if mainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, mainQuery)
+				t.Fail()
+			}
+			if c.WithCount && countQuery != c.CountQuery {
+				t.Errorf("expected countQ: %v, got: %v", c.CountQuery, countQuery)
+				t.Fail()
+			}
+
+			if len(args) != len(c.Args) {
+				t.Errorf("expected args: %v, got: %v", c.Args, args)
+				t.FailNow()
+			}
+			for i, v := range args {
+				_, ok := c.Args[i].([]int)
+				if ok {
+					continue
+				} else if c.Args[i] != v {
+					t.Errorf("expected arg: %v, got: %v", c.Args[i], v)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
+
+var testPageLimitsCases = []struct {
+	// Get params
+	Params string
+	Limits PageLimits
+
+	// Get response
+	MainQuery string
+	Err       error
+}{
+	{ // 1. Test default limit on empty restrictions block
+		Params: "ID??",
+		Limits: PageLimits{DefaultLimit: 20, MaxLimit: 100},
+
+		MainQuery: "select q.id from v_test q limit 20",
+		Err:       newError(""),
+	},
+	{ // 2. Test default limit on restrictions block without limit
+		Params: "ID??ID,desc,,5",
+		Limits: PageLimits{DefaultLimit: 20, MaxLimit: 100},
+
+		MainQuery: "select q.id from v_test q order by q.id desc limit 20 offset 5",
+		Err:       newError(""),
+	},
+	{ // 3. Test passed limit lower than maximum
+		Params: "ID??ID,desc,50,",
+		Limits: PageLimits{DefaultLimit: 20, MaxLimit: 100},
+
+		MainQuery: "select q.id from v_test q order by q.id desc limit 50",
+		Err:       newError(""),
+	},
+	{ // 4. Test passed limit greater than maximum
+		Params: "ID??ID,desc,500,",
+		Limits: PageLimits{DefaultLimit: 20, MaxLimit: 100},
+
+		MainQuery: "select q.id from v_test q order by q.id desc limit 100",
+		Err:       newError(""),
+	},
+	{ // 5. Test ERROR passed offset greater than maximum
+		Params: "ID??ID,desc,10,1001",
+		Limits: PageLimits{MaxOffset: 1000},
+
+		MainQuery: "",
+		Err:       newError("Selection offset exceeds maximum - 1001"),
+	},
+}
+
+func TestPageLimits(t *testing.T) {
+	for index, c := range testPageLimitsCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			mainQuery := ""
+			res, err := New(WithArgs(false), WithPageLimits(c.Limits)).Compile(context.Background(), Request{Model: TestModel{}, Target: "v_test", Params: c.Params})
+			if err != nil && err.Error() != c.Err.Error() {
+				t.Errorf("expected err: %v, got: %v", c.Err, err)
+				t.FailNow()
+			}
+			if res != nil {
+				mainQuery = res.MainQuery
+			}
+
+			if mainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, mainQuery)
+				t.Fail()
+			}
+		})
+	}
+
+	// legacy functions do not restrict selection size of unregistered model
+	mainQuery, _, _, err := Get(TestModel{}, "v_test", "ID??", false, false)
+	if err != nil || mainQuery != "select q.id from v_test q" {
+		t.Errorf("expected mainQ: %v, got: %v, err: %v", "select q.id from v_test q", mainQuery, err)
+	}
+}
+
+func TestSchemaPageLimits(t *testing.T) {
+	s, err := Register(TestModel{}, "v_test", WithSchemaPageLimits(PageLimits{DefaultLimit: 20, MaxLimit: 100, MaxOffset: 1000}))
+	if err != nil {
+		t.Errorf("expected err: %v, got: %v", nil, err)
+		t.FailNow()
+	}
+
+	cases := []struct {
+		Query     func() (string, error)
+		MainQuery string
+		Err       error
+	}{
+		{ // 1. Test default limit in Get
+			Query: func() (string, error) {
+				mainQ, _, _, err := Get(s, "v_test", "ID??", false, false)
+				return mainQ, err
+			},
+			MainQuery: "select q.id from v_test q limit 20",
+		},
+		{ // 2. Test maximum limit in Search
+			Query: func() (string, error) {
+				mainQ, _, _, err := s.Search("ID??ID,asc,500,", false, true, "content~~abc")
+				return mainQ, err
+			},
+			MainQuery: "select q.id from v_test q where (lower(q.content::text) like $1) order by q.id asc limit 100",
+		},
+		{ // 3. Test ERROR maximum offset in Get
+			Query: func() (string, error) {
+				mainQ, _, _, err := s.Get("ID??ID,asc,10,1001", false, false)
+				return mainQ, err
+			},
+			Err: newError("Selection offset exceeds maximum - 1001"),
+		},
+		{ // 4. Test page limits of compiler instead of schema ones
+			Query: func() (string, error) {
+				res, err := New(WithArgs(false), WithPageLimits(PageLimits{DefaultLimit: 5})).Compile(context.Background(), Request{Model: s, Params: "ID??"})
+				if err != nil {
+					return "", err
+				}
+				return res.MainQuery, nil
+			},
+			MainQuery: "select q.id from v_test q limit 5",
+		},
+	}
+	for index, c := range cases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			mainQuery, err := c.Query()
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+			if mainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, mainQuery)
+			}
+		})
+	}
+
+	limit, err := s.Limit("ID??ID,asc,500,")
+	if err != nil || limit == nil || *limit != 100 {
+		t.Errorf("expected limit: %v, got: %v, err: %v", 100, limit, err)
+	}
+	_, err = s.Offset("ID??ID,asc,10,1001")
+	if err == nil || err.Error() != newError("Selection offset exceeds maximum - 1001").Error() {
+		t.Errorf("expected err: %v, got: %v", newError("Selection offset exceeds maximum - 1001"), err)
+	}
+	_, err = Register(TestModel{}, "v_test", WithSchemaPageLimits(PageLimits{DefaultLimit: 200, MaxLimit: 100}))
+	if err == nil || err.Error() != newError("Default selection limit exceeds maximum limit").Error() {
+		t.Errorf("expected err: %v, got: %v", newError("Default selection limit exceeds maximum limit"), err)
+	}
+}
+
+func TestValidatePageLimits(t *testing.T) {
+	_, err := New(WithPageLimits(PageLimits{DefaultLimit: 200, MaxLimit: 100})).Compile(context.Background(), Request{Model: TestModel{}, Target: "v_test", Params: "ID??"})
+	if err == nil || err.Error() != newError("Default selection limit exceeds maximum limit").Error() {
+		t.Errorf("expected err: %v, got: %v", newError("Default selection limit exceeds maximum limit"), err)
+	}
+	_, err = New(WithPageLimits(PageLimits{MaxOffset: -1})).Compile(context.Background(), Request{Model: TestModel{}, Target: "v_test", Params: "ID??"})
+	if err == nil || err.Error() != newError("Invalid negative page limits").Error() {
+		t.Errorf("expected err: %v, got: %v", newError("Invalid negative page limits"), err)
+	}
+}
+
 var testSearchCases = []struct {
 	// Search params
 	ModelsMap    map[string]map[string]string
@@ -841,3 +1207,38 @@ func TestSearch(t *testing.T) {
 		})
 	}
 }
+
+func TestGetConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mainQuery, _, _, err := Get(TestModel{}, "v_test", "ID?ID==1?", false, false)
+			if err != nil || mainQuery != "select q.id from v_test q where q.id = 1" {
+				t.Errorf("unexpected mainQ: %v, err: %v", mainQuery, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkGet(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _, _, err := Get(TestModel{}, "v_test", "ID,content?(ID==1,2,13||isBool==true)*content!=anth?ID|isBool,desc,10,0", true, true)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _, _, err := Search(TestModel{}, "v_test", "ID?ID!=1?ID,asc,10,", true, true, "content~~smth||extraField~~anth")
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
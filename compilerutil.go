@@ -4,28 +4,65 @@ import (
 	"errors"
 	"reflect"
 	"sort"
-	"strings"
+	"strconv"
+	"sync"
 )
 
-// formDinamicModel forms a model containing fields for building query
-func formDinamicModel(model interface{}) map[string]string {
-	reflectModel := reflect.ValueOf(model)
-	modelTypes := reflectModel.Type()
+// Schemas of already handled models, keyed by model type
+var modelsCache sync.Map
 
-	fieldsMap := make(map[string]string, reflectModel.NumField())
-	for i := 0; i < reflectModel.NumField(); i++ { // json tag: sql tag
-		if modelTypes.Field(i).Type.Kind() == reflect.Struct { // handle nested struct
-			nestedFields := formDinamicModel(reflectModel.Field(i).Interface())
-			for k, v := range nestedFields { // merge nestedFields into main map
-				fieldsMap[k] = v
-			}
-			continue
-		}
+// formSchema forms a schema containing fields for building query
+func formSchema(model interface{}) (*Schema, error) {
+	if s, ok := model.(*Schema); ok { // registered model
+		return s, nil
+	}
+
+	modelType := reflect.TypeOf(model)
+	if s, ok := modelsCache.Load(modelType); ok {
+		return s.(*Schema), nil
+	}
+
+	s, err := newSchema(modelType, "", defaultSchemaConfig(), false)
+	if err != nil {
+		return nil, err
+	}
+	cached, _ := modelsCache.LoadOrStore(modelType, s)
+
+	return cached.(*Schema), nil
+}
+
+// formDinamicModel forms a model containing fields for building query.
+// Returned map is shared between calls and must not be modified
+func formDinamicModel(model interface{}) (map[string]string, error) {
+	s, err := formSchema(model)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.fieldsMap, nil
+}
+
+// applyPageLimit applies page limits to selection limit, nil limit means it is not passed
+func applyPageLimit(limit *int, limits PageLimits) *int {
+	if limit == nil && limits.DefaultLimit > 0 {
+		l := limits.DefaultLimit
+		limit = &l
+	}
+	if limit != nil && limits.MaxLimit > 0 && *limit > limits.MaxLimit {
+		l := limits.MaxLimit
+		limit = &l
+	}
+
+	return limit
+}
 
-		fieldsMap[strings.TrimSuffix(modelTypes.Field(i).Tag.Get("json"), ",omitempty")] = modelTypes.Field(i).Tag.Get("sql")
+// checkPageOffset checks selection offset against page limits
+func checkPageOffset(offset int, limits PageLimits) error {
+	if limits.MaxOffset > 0 && offset > limits.MaxOffset {
+		return newError("Selection offset exceeds maximum - " + strconv.Itoa(offset))
 	}
 
-	return fieldsMap
+	return nil
 }
 
 func addPGQuotes(str string) string {
@@ -0,0 +1,95 @@
+package compiler
+
+import (
+	"encoding/json"
+)
+
+// CountMode describes the way of counting result rows
+type CountMode int
+
+const (
+	CountNone      CountMode = iota // count query is not compiled
+	CountExact                      // separate count(*) query
+	CountWindow                     // total_count column of main query, read with ScanWithTotal
+	CountEstimated                  // planner estimate of rows count, read with Result.Count
+)
+
+// TotalCountColumn is name of column with total count of rows in CountWindow mode
+const TotalCountColumn = "total_count"
+
+// RowScanner describes result row of sql.Rows, sql.Row or pgx.Rows
+type RowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// ScanWithTotal scans row of query compiled in CountWindow mode into dest and returns total count of rows,
+// which is selected as the last column. Total count is unknown if selection returned no rows
+func ScanWithTotal(row RowScanner, dest ...interface{}) (int64, error) {
+	var total int64
+	if err := row.Scan(append(dest, &total)...); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// QueryRowFunc runs query with arguments and returns its single row (e.g. wrapped db.QueryRowContext)
+type QueryRowFunc func(query string, args ...interface{}) RowScanner
+
+// Count runs count query of result and returns count of result rows. In CountEstimated mode
+// estimate below threshold is recounted with exact count query, as well as missing statistics of target (e.g. of view)
+func (r *Result) Count(queryRow QueryRowFunc) (int64, error) {
+	switch r.countMode {
+	case CountExact:
+		return scanCount(queryRow(r.CountQuery, r.CountArgs...))
+	case CountEstimated:
+		var (
+			estimate int64
+			err      error
+		)
+		if r.isStatistics { // statistics of target, views and not analyzed tables have no statistics
+			estimate, err = scanCount(queryRow(r.CountQuery, r.CountArgs...))
+			if err == nil && estimate <= 0 {
+				return scanCount(queryRow(r.ExactCountQuery, r.ExactCountArgs...))
+			}
+		} else { // estimate of planner
+			estimate, err = scanPlanRows(queryRow(r.CountQuery, r.CountArgs...))
+		}
+		if err != nil || estimate >= r.threshold {
+			return estimate, err
+		}
+
+		return scanCount(queryRow(r.ExactCountQuery, r.ExactCountArgs...))
+	}
+
+	return 0, newError("Count query is not compiled")
+}
+
+// scanCount scans count of rows from row
+func scanCount(row RowScanner) (int64, error) {
+	var count int64
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// scanPlanRows scans rows estimate of EXPLAIN (FORMAT JSON) result
+func scanPlanRows(row RowScanner) (int64, error) {
+	var plan string
+	if err := row.Scan(&plan); err != nil {
+		return 0, err
+	}
+
+	var explain []struct {
+		Plan struct {
+			PlanRows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &explain); err != nil || len(explain) == 0 {
+		return 0, newError("Passed unexpected query plan")
+	}
+
+	return int64(explain[0].Plan.PlanRows), nil
+}
@@ -0,0 +1,261 @@
+package compiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testCountEstimatedCases = []struct {
+	// Compile params
+	Opts    []Option
+	Request Request
+
+	// Compile response
+	CountQuery      string
+	CountArgs       []interface{}
+	ExactCountQuery string
+	Err             error
+}{
+	{ // 1. Test target statistics without conditions
+		Request:         Request{Model: TestModel{}, Target: "v_test", Params: "ID??ID,asc,10,"},
+		CountQuery:      "select reltuples::bigint from pg_class where oid = $1::regclass",
+		CountArgs:       []interface{}{"v_test"},
+		ExactCountQuery: "select count(*) from (select 1 from v_test q) q",
+	},
+	{ // 2. Test planner estimate with conditions
+		Request:         Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID>1?ID,asc,10,"},
+		CountQuery:      "explain (format json) select 1 from v_test q where q.id > $1",
+		CountArgs:       []interface{}{1},
+		ExactCountQuery: "select count(*) from (select 1 from v_test q where q.id > $1) q",
+	},
+	{ // 3. Test planner estimate with scopes
+		Request:         Request{Model: TestModel{}, Target: "v_test", Params: "ID??", Scopes: []Scope{{Column: "tenant_id", Operator: "==", Value: 1}}},
+		CountQuery:      "explain (format json) select 1 from v_test q where q.tenant_id = $1",
+		CountArgs:       []interface{}{1},
+		ExactCountQuery: "select count(*) from (select 1 from v_test q where q.tenant_id = $1) q",
+	},
+	{ // 4. Test target statistics with arguments start index
+		Opts:            []Option{WithArgsIndex(3)},
+		Request:         Request{Model: TestModel{}, Target: "v_test", Params: "ID??"},
+		CountQuery:      "select reltuples::bigint from pg_class where oid = $1::regclass",
+		CountArgs:       []interface{}{"v_test"},
+		ExactCountQuery: "select count(*) from (select 1 from v_test q) q",
+	},
+	{ // 5. Test ERROR estimated count in MySQL dialect
+		Opts:    []Option{WithDialect(MySQL)},
+		Request: Request{Model: TestModel{}, Target: "v_test", Params: "ID??"},
+		Err:     newError("Passed unsupported estimated count in MySQL dialect"),
+	},
+}
+
+func TestCountEstimated(t *testing.T) {
+	for index, c := range testCountEstimatedCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			opts := append([]Option{WithCount(CountEstimated)}, c.Opts...)
+			res, err := New(opts...).Compile(context.Background(), c.Request)
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if res.CountQuery != c.CountQuery {
+				t.Errorf("expected countQ: %v, got: %v", c.CountQuery, res.CountQuery)
+				t.Fail()
+			}
+			if !reflect.DeepEqual(res.CountArgs, c.CountArgs) {
+				t.Errorf("expected count args: %v, got: %v", c.CountArgs, res.CountArgs)
+				t.Fail()
+			}
+			if res.ExactCountQuery != c.ExactCountQuery {
+				t.Errorf("expected exact countQ: %v, got: %v", c.ExactCountQuery, res.ExactCountQuery)
+				t.Fail()
+			}
+		})
+	}
+}
+
+// testCountRow imitates single column row of count query
+type testCountRow struct {
+	value interface{}
+}
+
+func (r testCountRow) Scan(dest ...interface{}) error {
+	switch d := dest[0].(type) {
+	case *int64:
+		v, ok := r.value.(int64)
+		if !ok {
+			return errors.New("unexpected column type")
+		}
+		*d = v
+	case *string:
+		v, ok := r.value.(string)
+		if !ok {
+			return errors.New("unexpected column type")
+		}
+		*d = v
+	}
+
+	return nil
+}
+
+var testResultCountCases = []struct {
+	// Count params
+	Opts   []Option
+	Params string                 // query string, %s is replaced by cursor of ID
+	Cursor []interface{}          // cursor values of ID
+	Rows   map[string]interface{} // query prefix: row value
+
+	// Count response
+	Count     int64
+	ExactArgs []interface{} // arguments of exact count query
+	Err       error
+}{
+	{ // 1. Test exact count
+		Opts:      []Option{WithCount(CountExact)},
+		Params:    "ID?ID>1?",
+		Rows:      map[string]interface{}{"select count": int64(15)},
+		Count:     15,
+		ExactArgs: []interface{}{1},
+	},
+	{ // 2. Test target statistics above threshold
+		Opts:   []Option{WithCount(CountEstimated), WithEstimateThreshold(1000)},
+		Params: "ID??",
+		Rows:   map[string]interface{}{"select reltuples": int64(250000), "select count": int64(249990)},
+		Count:  250000,
+	},
+	{ // 3. Test missing statistics of view is recounted
+		Opts:   []Option{WithCount(CountEstimated)},
+		Params: "ID??",
+		Rows:   map[string]interface{}{"select reltuples": int64(-1), "select count": int64(42)},
+		Count:  42,
+	},
+	{ // 4. Test planner estimate above threshold
+		Opts:   []Option{WithCount(CountEstimated), WithEstimateThreshold(1000)},
+		Params: "ID?ID>1?",
+		Rows:   map[string]interface{}{"explain": `[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 52000}}]`, "select count": int64(51234)},
+		Count:  52000,
+	},
+	{ // 5. Test planner estimate below threshold is recounted
+		Opts:      []Option{WithCount(CountEstimated), WithEstimateThreshold(1000)},
+		Params:    "ID?ID>1?",
+		Rows:      map[string]interface{}{"explain": `[{"Plan": {"Plan Rows": 12}}]`, "select count": int64(7)},
+		Count:     7,
+		ExactArgs: []interface{}{1},
+	},
+	{ // 6. Test ERROR unexpected query plan
+		Opts:   []Option{WithCount(CountEstimated)},
+		Params: "ID?ID>1?",
+		Rows:   map[string]interface{}{"explain": `{}`},
+		Err:    newError("Passed unexpected query plan"),
+	},
+	{ // 7. Test ERROR count query is not compiled
+		Opts:   []Option{WithCount(CountWindow)},
+		Params: "ID??",
+		Err:    newError("Count query is not compiled"),
+	},
+	{ // 8. Test planner estimate below threshold with cursor is recounted without cursor arguments
+		Opts:      []Option{WithCount(CountEstimated), WithEstimateThreshold(1000)},
+		Params:    "ID?count>1?ID,asc,10,,%s",
+		Cursor:    []interface{}{7},
+		Rows:      map[string]interface{}{"explain": `[{"Plan": {"Plan Rows": 12}}]`, "select count": int64(5)},
+		Count:     5,
+		ExactArgs: []interface{}{1},
+	},
+	{ // 9. Test missing statistics of view with cursor is recounted without cursor arguments
+		Opts:   []Option{WithCount(CountEstimated)},
+		Params: "ID??ID,asc,10,,%s",
+		Cursor: []interface{}{7},
+		Rows:   map[string]interface{}{"select reltuples": int64(-1), "select count": int64(42)},
+		Count:  42,
+	},
+}
+
+func TestResultCount(t *testing.T) {
+	for index, c := range testResultCountCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			params := c.Params
+			if c.Cursor != nil {
+				token, err := EncodeCursor([]string{"ID"}, c.Cursor)
+				if err != nil {
+					t.Errorf("expected err: %v, got: %v", nil, err)
+					t.FailNow()
+				}
+				params = fmt.Sprintf(params, token)
+			}
+			res, err := New(c.Opts...).Compile(context.Background(), Request{Model: TestModel{}, Target: "v_test", Params: params})
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			count, err := res.Count(func(query string, args ...interface{}) RowScanner {
+				if strings.HasPrefix(query, "select count") && len(args)+len(c.ExactArgs) > 0 && !reflect.DeepEqual(args, c.ExactArgs) {
+					t.Errorf("expected exact count args: %v, got: %v", c.ExactArgs, args)
+				}
+				for prefix, value := range c.Rows {
+					if strings.HasPrefix(query, prefix) {
+						return testCountRow{value: value}
+					}
+				}
+				return testCountRow{}
+			})
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+			if count != c.Count {
+				t.Errorf("expected count: %v, got: %v", c.Count, count)
+			}
+		})
+	}
+}
+
+// testRow imitates result row with id and total_count columns
+type testRow struct {
+	id    int64
+	total int64
+}
+
+func (r testRow) Scan(dest ...interface{}) error {
+	if len(dest) != 2 {
+		return errors.New("unexpected columns count")
+	}
+
+	*dest[0].(*int64) = r.id
+	*dest[1].(*int64) = r.total
+	return nil
+}
+
+func TestScanWithTotal(t *testing.T) {
+	var id int64
+	total, err := ScanWithTotal(testRow{id: 3, total: 42}, &id)
+	if err != nil {
+		t.Errorf("expected err: %v, got: %v", nil, err)
+		t.FailNow()
+	}
+	if id != 3 || total != 42 {
+		t.Errorf("expected id: %v, total: %v, got id: %v, total: %v", 3, 42, id, total)
+	}
+
+	_, err = ScanWithTotal(testRow{}, &id, &id)
+	if err == nil || err.Error() != "unexpected columns count" {
+		t.Errorf("expected err: %v, got: %v", "unexpected columns count", err)
+	}
+}
@@ -0,0 +1,203 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// cursor describes position of keyset selection - values of sort fields of the last returned row
+type cursor struct {
+	Fields []string      `json:"f"`
+	Values []interface{} `json:"v"`
+}
+
+// EncodeCursor encodes cursor of selection after row with passed values of sort fields
+func EncodeCursor(fields []string, values []interface{}) (string, error) {
+	if len(fields) == 0 || len(fields) != len(values) {
+		return "", newError("Passed cursor values not matching sort fields")
+	}
+
+	data, err := json.Marshal(cursor{Fields: fields, Values: values})
+	if err != nil {
+		return "", newError("Passed unexpected cursor values - " + err.Error())
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// NextCursor encodes cursor of selection after the last returned row. Sort fields are read
+// from restrictions block of query q with schema default sort and primary key, their values are read from row by API field names
+func NextCursor(model interface{}, q string, row interface{}) (string, error) {
+	s, err := formSchema(model)
+	if err != nil {
+		return "", err
+	}
+	q, err = encodeRelationBlocks(q)
+	if err != nil {
+		return "", err
+	}
+	blocks := strings.Split(q, "?")
+	if len(blocks) < 3 {
+		return "", newError("Passed unexpected query string - " + q)
+	}
+	groupBlock := ""
+	if len(blocks) > 3 {
+		groupBlock = blocks[3]
+	}
+	sel, _, err := parseSelection(blocks[0], groupBlock)
+	if err != nil {
+		return "", err
+	}
+	rests := strings.Split(blocks[2], ",")
+	tokens, err := s.sortTokens(rests[0], "asc", sel, true)
+	if err != nil {
+		return "", err
+	}
+	if len(tokens) == 0 {
+		return "", newError("Cursor requires selection order fields")
+	}
+
+	fields := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		f, _, _, err := parseSortToken(token, "asc")
+		if err != nil {
+			return "", err
+		}
+		if s.valueExpr(f) == "" {
+			return "", newError("Passed unexpected selection order field - " + f)
+		}
+		fields = append(fields, f)
+	}
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		return "", newError("Passed unexpected cursor row - " + err.Error())
+	}
+	var rowMap map[string]interface{}
+	if err := unmarshalNumbers(data, &rowMap); err != nil {
+		return "", newError("Passed unexpected cursor row - " + err.Error())
+	}
+
+	values := make([]interface{}, 0, len(fields))
+	for _, f := range fields {
+		value, ok := lookupPath(rowMap, f)
+		if !ok || value == nil {
+			return "", newError("Passed cursor row without sort field value - " + f)
+		}
+		values = append(values, value)
+	}
+
+	return EncodeCursor(fields, values)
+}
+
+// decodeCursor decodes cursor token and checks it was formed for passed sort fields
+func decodeCursor(token string, fields []string) (*cursor, error) {
+	if len(fields) == 0 {
+		return nil, newError("Cursor requires selection order fields")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, newError("Passed unexpected cursor - " + token)
+	}
+	var c cursor
+	if err := unmarshalNumbers(data, &c); err != nil || len(c.Values) != len(c.Fields) {
+		return nil, newError("Passed unexpected cursor - " + token)
+	}
+	if strings.Join(c.Fields, "|") != strings.Join(fields, "|") {
+		return nil, newError("Passed cursor of different selection order fields")
+	}
+	for i, v := range c.Values {
+		if n, ok := v.(json.Number); ok { // restore numeric type of value
+			if i64, err := n.Int64(); err == nil {
+				c.Values[i] = i64
+			} else if f64, err := n.Float64(); err == nil {
+				c.Values[i] = f64
+			}
+		}
+	}
+
+	return &c, nil
+}
+
+// condition returns keyset predicate selecting rows after cursor position in selection order.
+// Fields of the same selection order are compared as row, mixed orders are expanded into OR-chain
+func (c *cursor) condition(d Dialect, r *restrictions, index int) (string, []interface{}) {
+	isUniform := true
+	for _, order := range r.orders {
+		if order != r.orders[0] {
+			isUniform = false
+		}
+	}
+
+	if isUniform {
+		args := make([]interface{}, len(c.Values))
+		copy(args, c.Values)
+		placeholders := bindArgs(d, index, args)
+
+		return "(" + strings.Join(r.exprs, ", ") + ")" + seekOperator(r.orders[0]) + "(" + placeholders + ")", args
+	}
+
+	var (
+		args    []interface{}
+		orConds []string
+	)
+	bind := func(i int) string { // bind value of field as a separate argument
+		arg := []interface{}{c.Values[i]}
+		placeholder := bindArgs(d, index+len(args), arg)
+		args = append(args, arg...)
+		return placeholder
+	}
+	for i := range r.exprs {
+		var andConds []string
+		for j := 0; j < i; j++ {
+			andConds = append(andConds, r.exprs[j]+" = "+bind(j))
+		}
+		andConds = append(andConds, r.exprs[i]+seekOperator(r.orders[i])+bind(i))
+
+		if len(andConds) > 1 {
+			orConds = append(orConds, "("+strings.Join(andConds, " and ")+")")
+		} else {
+			orConds = append(orConds, andConds[0])
+		}
+	}
+
+	return "(" + strings.Join(orConds, " or ") + ")", args
+}
+
+// seekOperator returns comparison operator of rows after cursor in selection order
+func seekOperator(order string) string {
+	if order == "desc" {
+		return " < "
+	}
+
+	return " > "
+}
+
+// unmarshalNumbers decodes JSON with numbers kept as json.Number
+func unmarshalNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(v)
+}
+
+// lookupPath returns value of dotted path (e.g. author.name) in decoded JSON object
+func lookupPath(m map[string]interface{}, path string) (interface{}, bool) {
+	if v, ok := m[path]; ok {
+		return v, true
+	}
+
+	i := strings.Index(path, ".")
+	if i < 0 {
+		return nil, false
+	}
+	nested, ok := m[path[:i]].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	return lookupPath(nested, path[i+1:])
+}
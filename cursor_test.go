@@ -0,0 +1,234 @@
+package compiler
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type TestCursorRow struct {
+	ID      *int64  `json:"ID,omitempty" sql:"id"`
+	Content *string `json:"content,omitempty" sql:"content"`
+	Count   *int    `json:"count,omitempty" sql:"count"`
+}
+
+var testCursorCases = []struct {
+	// Cursor params
+	Fields []string
+	Values []interface{}
+	Opts   []Option
+	Params string // query string, %s is replaced by cursor
+
+	// Compile response
+	MainQuery  string
+	CountQuery string
+	Args       []interface{}
+	Err        error
+}{
+	{ // 1. Test ascending selection after cursor
+		Fields:    []string{"count", "ID"},
+		Values:    []interface{}{5, 100},
+		Params:    "ID??count|ID,asc,10,,%s",
+		MainQuery: "select q.id from v_test q where (q.count, q.id) > ($1, $2) order by q.count asc, q.id asc limit 10",
+		Args:      []interface{}{int64(5), int64(100)},
+	},
+	{ // 2. Test descending selection after cursor with conditions
+		Fields:     []string{"content", "ID"},
+		Values:     []interface{}{"abc", 7},
+		Opts:       []Option{WithCount(CountExact)},
+		Params:     "ID?ID>1||isBool==true?content|ID,desc,10,,%s",
+		MainQuery:  "select q.id from v_test q where (q.id > $1 or q.is_bool = $2) and (q.content, q.id) < ($3, $4) order by q.content desc, q.id desc limit 10",
+		CountQuery: "select count(*) from (select 1 from v_test q where q.id > $1 or q.is_bool = $2) q",
+		Args:       []interface{}{1, true, "abc", int64(7)},
+	},
+	{ // 3. Test cursor with MySQL dialect
+		Fields:    []string{"ID"},
+		Values:    []interface{}{100},
+		Opts:      []Option{WithDialect(MySQL)},
+		Params:    "ID?count>=2?ID,asc,5,,%s",
+		MainQuery: "select q.id from v_test q where (q.count >= ?) and (q.id) > (?) order by q.id asc limit 5",
+		Args:      []interface{}{2, int64(100)},
+	},
+	{ // 4. Test cursor with mixed selection orders
+		Fields:    []string{"count", "ID"},
+		Values:    []interface{}{5, 100},
+		Params:    "ID??count:desc|ID:asc,,10,,%s",
+		MainQuery: "select q.id from v_test q where (q.count < $1 or (q.count = $2 and q.id > $3)) order by q.count desc, q.id asc limit 10",
+		Args:      []interface{}{int64(5), int64(5), int64(100)},
+	},
+	{ // 5. Test window count of rows before cursor
+		Fields:    []string{"ID"},
+		Values:    []interface{}{100},
+		Opts:      []Option{WithCount(CountWindow)},
+		Params:    "ID?count>2?ID,asc,10,,%s",
+		MainQuery: "select q.id, (select count(*) from (select 1 from v_test q where q.count > $1) q) as total_count from v_test q where (q.count > $1) and (q.id) > ($2) order by q.id asc limit 10",
+		Args:      []interface{}{2, int64(100)},
+	},
+	{ // 6. Test ERROR window count with cursor in MySQL dialect
+		Fields: []string{"ID"},
+		Values: []interface{}{100},
+		Opts:   []Option{WithDialect(MySQL), WithCount(CountWindow)},
+		Params: "ID??ID,asc,10,,%s",
+		Err:    newError("Passed unsupported window count with cursor in positional placeholders"),
+	},
+	{ // 7. Test ERROR cursor of different sort fields
+		Fields: []string{"ID"},
+		Values: []interface{}{100},
+		Params: "ID??count|ID,asc,10,,%s",
+		Err:    newError("Passed cursor of different selection order fields"),
+	},
+	{ // 8. Test ERROR cursor with offset
+		Fields: []string{"ID"},
+		Values: []interface{}{100},
+		Params: "ID??ID,asc,10,20,%s",
+		Err:    newError("Passed selection offset with cursor"),
+	},
+	{ // 9. Test ERROR cursor without arguments mode
+		Fields: []string{"ID"},
+		Values: []interface{}{100},
+		Opts:   []Option{WithArgs(false)},
+		Params: "ID??ID,asc,10,,%s",
+		Err:    newError("Passed cursor without arguments mode"),
+	},
+	{ // 10. Test ERROR unexpected cursor
+		Params: "ID??ID,asc,10,,%s",
+		Err:    newError("Passed unexpected cursor - !!"),
+	},
+}
+
+func TestCursor(t *testing.T) {
+	for index, c := range testCursorCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			token := "!!"
+			if c.Fields != nil {
+				var err error
+				token, err = EncodeCursor(c.Fields, c.Values)
+				if err != nil {
+					t.Errorf("expected err: %v, got: %v", nil, err)
+					t.FailNow()
+				}
+			}
+
+			params := strings.Replace(c.Params, "%s", token, 1)
+			res, err := New(c.Opts...).Compile(context.Background(), Request{Model: TestModel{}, Target: "v_test", Params: params})
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if res.MainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, res.MainQuery)
+				t.Fail()
+			}
+			if res.CountQuery != c.CountQuery {
+				t.Errorf("expected countQ: %v, got: %v", c.CountQuery, res.CountQuery)
+				t.Fail()
+			}
+			if !reflect.DeepEqual(res.Args, c.Args) {
+				t.Errorf("expected args: %v, got: %v", c.Args, res.Args)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	id, content := int64(42), "last"
+	row := TestCursorRow{ID: &id, Content: &content}
+
+	token, err := NextCursor(TestModel{}, "ID,content??content|ID,asc,10,", row)
+	if err != nil {
+		t.Errorf("expected err: %v, got: %v", nil, err)
+		t.FailNow()
+	}
+	res, err := New().Compile(context.Background(), Request{Model: TestModel{}, Target: "v_test", Params: "ID,content??content|ID,asc,10,," + token})
+	if err != nil {
+		t.Errorf("expected err: %v, got: %v", nil, err)
+		t.FailNow()
+	}
+
+	expected := "select q.id, q.content from v_test q where (q.content, q.id) > ($1, $2) order by q.content asc, q.id asc limit 10"
+	if res.MainQuery != expected {
+		t.Errorf("expected mainQ: %v, got: %v", expected, res.MainQuery)
+	}
+	if !reflect.DeepEqual(res.Args, []interface{}{"last", int64(42)}) {
+		t.Errorf("expected args: %v, got: %v", []interface{}{"last", int64(42)}, res.Args)
+	}
+
+	_, err = NextCursor(TestModel{}, "ID??count|ID,asc,10,", row)
+	if err == nil || err.Error() != newError("Passed cursor row without sort field value - count").Error() {
+		t.Errorf("expected err: %v, got: %v", newError("Passed cursor row without sort field value - count"), err)
+	}
+}
+
+func TestQueryBuilderAfter(t *testing.T) {
+	token, _ := EncodeCursor([]string{"ID"}, []interface{}{10})
+
+	params, err := Query().Select("ID").OrderBy("ID").Limit(10).After(token).Params()
+	if err != nil {
+		t.Errorf("expected err: %v, got: %v", nil, err)
+		t.FailNow()
+	}
+	if expected := "ID??ID,,10,," + token; params != expected {
+		t.Errorf("expected params: %v, got: %v", expected, params)
+	}
+}
+
+func TestNextCursorWithPrimaryKey(t *testing.T) {
+	s, err := Register(TestPkModel{}, "v_test", WithDefaultSort("createdAt:desc"))
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+
+	id, createdAt := int64(7), "2024-01-02T00:00:00Z"
+	token, err := NextCursor(s, "ID??,,10,", TestPkModel{ID: &id, CreatedAt: &createdAt})
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+
+	mainQuery, _, args, err := s.Get("ID??,,10,,"+token, false, true)
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+	expected := "select q.id from v_test q where (q.created_at, q.id) < ($1, $2) order by q.created_at desc, q.id desc limit 10"
+	if mainQuery != expected {
+		t.Errorf("expected mainQ: %v, got: %v", expected, mainQuery)
+	}
+	if !reflect.DeepEqual(args, []interface{}{createdAt, id}) {
+		t.Errorf("expected args: %v, got: %v", []interface{}{createdAt, id}, args)
+	}
+}
+
+func TestCursorParts(t *testing.T) {
+	token, _ := EncodeCursor([]string{"ID"}, []interface{}{100})
+	res, err := New(WithCount(CountExact)).Compile(context.Background(), Request{Model: TestModel{}, Target: "v_test", Params: "ID?count>2?ID,asc,10,," + token})
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+
+	if res.Where != "q.count > $1" || !reflect.DeepEqual(res.WhereArgs, []interface{}{2}) {
+		t.Errorf("expected where: %v %v, got: %v %v", "q.count > $1", []interface{}{2}, res.Where, res.WhereArgs)
+	}
+	if res.Seek != "(q.id) > ($2)" || !reflect.DeepEqual(res.SeekArgs, []interface{}{int64(100)}) {
+		t.Errorf("expected seek: %v %v, got: %v %v", "(q.id) > ($2)", []interface{}{int64(100)}, res.Seek, res.SeekArgs)
+	}
+	if expected := "select count(*) from (select 1 from v_test q where q.count > $1) q"; res.CountQuery != expected {
+		t.Errorf("expected countQ: %v, got: %v", expected, res.CountQuery)
+	}
+	if !reflect.DeepEqual(res.CountArgs, []interface{}{2}) {
+		t.Errorf("expected count args: %v, got: %v", []interface{}{2}, res.CountArgs)
+	}
+
+	_, _, _, err = Get(TestModel{}, "v_test", "ID?count>2?ID,asc,10,,"+token, true, true)
+	if expected := newError("Passed cursor with count query, use Compile with Result.CountArgs"); err == nil || err.Error() != expected.Error() {
+		t.Errorf("expected err: %v, got: %v", expected, err)
+	}
+}
@@ -0,0 +1,318 @@
+package compiler
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Dialect describes SQL syntax of target database
+type Dialect interface {
+	// Name returns name of database
+	Name() string
+	// Placeholder returns placeholder of argument by its index, starting from 1
+	Placeholder(index int) string
+	// ExpandArrays reports if array value is passed as separate argument for each element
+	ExpandArrays() bool
+	// ArrayValues formats array values inlined into query
+	ArrayValues(values string) string
+	// InArray returns condition of field inclusion into list of values or placeholders
+	InArray(field, values string, isNot bool) string
+	// Overlaps returns condition of array field overlapping with list of values or placeholders
+	Overlaps(field, values string, isNot bool) (string, error)
+	// Includes returns condition of array field including all values or placeholders
+	Includes(field, values string) (string, error)
+	// JSONField returns expression of JSON key text value
+	JSONField(field, key string) string
+	// Like returns case-insensitive LIKE condition on field casted to text
+	Like(field, pattern string) string
+	// SortExpr returns ORDER BY element of field with selection order and nulls placement (first, last or empty)
+	SortExpr(field, order, nulls string) string
+}
+
+// Supported dialects
+var (
+	PostgreSQL Dialect = postgresDialect{}
+	MySQL      Dialect = mysqlDialect{}
+	SQLite     Dialect = sqliteDialect{}
+	ClickHouse Dialect = clickhouseDialect{}
+)
+
+// postgresDialect describes PostgreSQL syntax, used by default
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return "PostgreSQL"
+}
+
+func (postgresDialect) Placeholder(index int) string {
+	return "$" + strconv.Itoa(index)
+}
+
+func (postgresDialect) ExpandArrays() bool {
+	return false
+}
+
+func (postgresDialect) ArrayValues(values string) string {
+	return values
+}
+
+func (postgresDialect) InArray(field, values string, isNot bool) string {
+	if isNot {
+		return "not " + field + " = any(" + values + ")"
+	}
+
+	return field + " = any(" + values + ")"
+}
+
+func (postgresDialect) Overlaps(field, values string, isNot bool) (string, error) {
+	if isNot {
+		return "not " + field + " && " + values, nil
+	}
+
+	return field + " && " + values, nil
+}
+
+func (postgresDialect) Includes(field, values string) (string, error) {
+	return field + " @> " + values, nil
+}
+
+func (postgresDialect) JSONField(field, key string) string {
+	return field + "->>'" + key + "'"
+}
+
+func (postgresDialect) Like(field, pattern string) string {
+	return "lower(" + field + "::text) like " + pattern
+}
+
+func (postgresDialect) SortExpr(field, order, nulls string) string {
+	return sortExpr(field, order, nulls)
+}
+
+// mysqlDialect describes MySQL syntax
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return "MySQL"
+}
+
+func (mysqlDialect) Placeholder(index int) string {
+	return "?"
+}
+
+func (mysqlDialect) ExpandArrays() bool {
+	return true
+}
+
+func (mysqlDialect) ArrayValues(values string) string {
+	return values
+}
+
+func (mysqlDialect) InArray(field, values string, isNot bool) string {
+	return inList(field, values, isNot)
+}
+
+func (d mysqlDialect) Overlaps(field, values string, isNot bool) (string, error) {
+	return "", unsupportedOverlaps(d, isNot)
+}
+
+func (d mysqlDialect) Includes(field, values string) (string, error) {
+	return "", newError("Passed unsupported INCLUDES operator in " + d.Name() + " dialect")
+}
+
+func (mysqlDialect) JSONField(field, key string) string {
+	return field + "->>'$." + key + "'"
+}
+
+func (mysqlDialect) Like(field, pattern string) string {
+	return "lower(cast(" + field + " as char)) like " + pattern
+}
+
+func (mysqlDialect) SortExpr(field, order, nulls string) string { // MySQL has no NULLS FIRST/LAST clause
+	switch nulls {
+	case "first":
+		return field + " is null desc, " + field + " " + order
+	case "last":
+		return field + " is null asc, " + field + " " + order
+	}
+
+	return field + " " + order
+}
+
+// sqliteDialect describes SQLite syntax
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return "SQLite"
+}
+
+func (sqliteDialect) Placeholder(index int) string {
+	return "?"
+}
+
+func (sqliteDialect) ExpandArrays() bool {
+	return true
+}
+
+func (sqliteDialect) ArrayValues(values string) string {
+	return values
+}
+
+func (sqliteDialect) InArray(field, values string, isNot bool) string {
+	return inList(field, values, isNot)
+}
+
+func (d sqliteDialect) Overlaps(field, values string, isNot bool) (string, error) {
+	return "", unsupportedOverlaps(d, isNot)
+}
+
+func (d sqliteDialect) Includes(field, values string) (string, error) {
+	return "", newError("Passed unsupported INCLUDES operator in " + d.Name() + " dialect")
+}
+
+func (sqliteDialect) JSONField(field, key string) string {
+	return "json_extract(" + field + ", '$." + key + "')"
+}
+
+func (sqliteDialect) Like(field, pattern string) string { // SQLite has no default escape character
+	return "lower(cast(" + field + " as text)) like " + pattern + ` escape '\'`
+}
+
+func (sqliteDialect) SortExpr(field, order, nulls string) string {
+	return sortExpr(field, order, nulls)
+}
+
+// ArgEncoder converts array value into driver argument. Arrays are passed as Go slices by default,
+// which are encoded natively by pgx and clickhouse-go drivers, lib/pq arrays are encoded by pqargs adapter
+type ArgEncoder func(values interface{}) interface{}
+
+// arrayArgs converts array value into arguments of dialect
+func arrayArgs(d Dialect, values interface{}) []interface{} {
+	if d.ExpandArrays() {
+		return expandArray(values)
+	}
+	if cd, ok := d.(compilerDialect); ok && cd.encode != nil {
+		values = cd.encode(values)
+	}
+
+	return []interface{}{values}
+}
+
+// expandArray converts array value into list of arguments
+func expandArray(values interface{}) []interface{} {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []interface{}{values}
+	}
+
+	args := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		args = append(args, v.Index(i).Interface())
+	}
+
+	return args
+}
+
+// sortExpr returns ORDER BY element with NULLS FIRST/LAST clause
+func sortExpr(field, order, nulls string) string {
+	if nulls != "" {
+		return field + " " + order + " nulls " + nulls
+	}
+
+	return field + " " + order
+}
+
+// inList returns IN condition of field
+func inList(field, values string, isNot bool) string {
+	if isNot {
+		return field + " not in (" + values + ")"
+	}
+
+	return field + " in (" + values + ")"
+}
+
+// unsupportedOverlaps returns error of OVERLAPS operator in dialect without array type
+func unsupportedOverlaps(d Dialect, isNot bool) error {
+	if isNot {
+		return newError("Passed unsupported NOT OVERLAPS operator in " + d.Name() + " dialect")
+	}
+
+	return newError("Passed unsupported OVERLAPS operator in " + d.Name() + " dialect")
+}
+
+// PlaceholderStyle describes format of arguments placeholders
+type PlaceholderStyle int
+
+const (
+	PlaceholderDefault  PlaceholderStyle = iota // placeholders of dialect
+	PlaceholderDollar                           // $1, $2
+	PlaceholderQuestion                         // ?, ?
+	PlaceholderNamed                            // :p1, :p2, arguments are passed as sql.NamedArg
+	PlaceholderAtP                              // @p1, @p2
+)
+
+// compilerDialect applies compiler options to dialect - placeholders style, starting index and array encoder
+type compilerDialect struct {
+	Dialect
+	style    PlaceholderStyle
+	start    int
+	encode   ArgEncoder
+	nullArgs bool // nil arguments of NULL conditions without placeholders, kept for Get and Search
+}
+
+func (d compilerDialect) Placeholder(index int) string {
+	index = index + d.start - 1
+	switch d.style {
+	case PlaceholderDollar:
+		return "$" + strconv.Itoa(index)
+	case PlaceholderQuestion:
+		return "?"
+	case PlaceholderNamed:
+		return ":" + d.argName(index)
+	case PlaceholderAtP:
+		return "@" + d.argName(index)
+	}
+
+	return d.Dialect.Placeholder(index)
+}
+
+// argName returns name of argument by its index
+func (d compilerDialect) argName(index int) string {
+	return "p" + strconv.Itoa(index)
+}
+
+// isNullArgs reports whether NULL conditions add nil arguments, as arguments of Get and Search do
+func isNullArgs(d Dialect) bool {
+	cd, ok := d.(compilerDialect)
+	return ok && cd.nullArgs
+}
+
+// bindArgs returns placeholders of arguments starting from index, joined with comma.
+// Arguments are converted to sql.NamedArg for named placeholders
+func bindArgs(d Dialect, index int, args []interface{}) string {
+	pd, isNamed := d.(compilerDialect)
+	isNamed = isNamed && pd.style == PlaceholderNamed
+
+	list := make([]string, 0, len(args))
+	for i := range args {
+		list = append(list, d.Placeholder(index+i))
+		if isNamed {
+			args[i] = sql.Named(pd.argName(index+i+pd.start-1), args[i])
+		}
+	}
+
+	return strings.Join(list, ", ")
+}
+
+// namedArgs returns named arguments of list, skipping values without placeholder (e.g. of NULL conditions)
+func namedArgs(args []interface{}) []interface{} {
+	var named []interface{}
+	for _, arg := range args {
+		if _, ok := arg.(sql.NamedArg); ok {
+			named = append(named, arg)
+		}
+	}
+
+	return named
+}
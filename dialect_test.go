@@ -0,0 +1,247 @@
+package compiler
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+var testDialectCases = []struct {
+	// Compile params
+	Dialect Dialect
+	Request Request
+
+	// Compile response
+	MainQuery string
+	Args      []interface{}
+	Err       error
+}{
+	{ // 1. Test MySQL placeholders
+		Dialect:   MySQL,
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1*content!=test?ID,desc,10,"},
+		MainQuery: "select q.id from v_test q where q.id = ? and q.content != ? order by q.id desc limit 10",
+		Args:      []interface{}{1, "test"},
+	},
+	{ // 2. Test MySQL array values are expanded into list
+		Dialect:   MySQL,
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1,2,3*count!=4,5?"},
+		MainQuery: "select q.id from v_test q where q.id in (?, ?, ?) and q.count not in (?, ?)",
+		Args:      []interface{}{1, 2, 3, 4, 5},
+	},
+	{ // 3. Test MySQL JSON field and search
+		Dialect:   MySQL,
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?content==key^^val?", Search: "content~~smth"},
+		MainQuery: "select q.id from v_test q where (lower(cast(q.content as char)) like ?) and q.content->>'$.key' = ?",
+		Args:      []interface{}{"%smth%", "val"},
+	},
+	{ // 4. Test MySQL scopes
+		Dialect:   MySQL,
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1?", Scopes: []Scope{{Column: "tenant_id", Operator: "==", Value: []int{5, 6}}}},
+		MainQuery: "select q.id from v_test q where q.tenant_id in (?, ?) and (q.id = ?)",
+		Args:      []interface{}{5, 6, 1},
+	},
+	{ // 5. Test SQLite JSON field and search
+		Dialect:   SQLite,
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?content==key^^val*ID==1,2?", Search: "content~~smth"},
+		MainQuery: `select q.id from v_test q where (lower(cast(q.content as text)) like ? escape '\') and json_extract(q.content, '$.key') = ? and q.id in (?, ?)`,
+		Args:      []interface{}{"%smth%", "val", 1, 2},
+	},
+	{ // 6. Test MySQL nulls placement
+		Dialect:   MySQL,
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID??content:desc:nullslast|ID,,,"},
+		MainQuery: "select q.id from v_test q order by q.content is null asc, q.content desc, q.id asc",
+	},
+	{ // 7. Test PostgreSQL dialect
+		Dialect:   PostgreSQL,
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID>>1,2?"},
+		MainQuery: "select q.id from v_test q where q.id && $1",
+	},
+	{ // 8. Test PostgreSQL INCLUDES operator
+		Dialect:   PostgreSQL,
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID->>1,2*count==3?"},
+		MainQuery: "select q.id from v_test q where q.id @> $1 and q.count = $2",
+	},
+	{ // 9. Test ERROR MySQL INCLUDES operator
+		Dialect: MySQL,
+		Request: Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID->>1,2?"},
+		Err:     newError("Passed unsupported INCLUDES operator in MySQL dialect"),
+	},
+	{ // 10. Test ERROR MySQL OVERLAPS operator
+		Dialect: MySQL,
+		Request: Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID>>1,2?"},
+		Err:     newError("Passed unsupported OVERLAPS operator in MySQL dialect"),
+	},
+	{ // 11. Test ERROR SQLite NOT OVERLAPS scope
+		Dialect: SQLite,
+		Request: Request{Model: TestModel{}, Target: "v_test", Params: "ID??", Scopes: []Scope{{Column: "tags", Operator: "!!", Value: []string{"a"}}}},
+		Err:     newError("Passed unsupported NOT OVERLAPS operator in SQLite dialect"),
+	},
+	{ // 12. Test MySQL distinct selection
+		Dialect:   MySQL,
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "distinct,content??"},
+		MainQuery: "select distinct q.content from v_test q",
+	},
+	{ // 13. Test ERROR MySQL distinct on selection
+		Dialect: MySQL,
+		Request: Request{Model: TestModel{}, Target: "v_test", Params: "distinct(content),ID??"},
+		Err:     newError("Passed unsupported DISTINCT ON in MySQL dialect"),
+	},
+}
+
+func TestDialects(t *testing.T) {
+	for index, c := range testDialectCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			res, err := New(WithDialect(c.Dialect)).Compile(context.Background(), c.Request)
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if res.MainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, res.MainQuery)
+				t.Fail()
+			}
+			if c.Args != nil {
+				if len(res.Args) != len(c.Args) {
+					t.Errorf("expected args: %v, got: %v", c.Args, res.Args)
+					t.FailNow()
+				}
+				for i, v := range res.Args {
+					if c.Args[i] != v {
+						t.Errorf("expected arg: %v, got: %v", c.Args[i], v)
+						t.Fail()
+					}
+				}
+			}
+		})
+	}
+}
+
+var testPlaceholdersCases = []struct {
+	// Compile params
+	Opts    []Option
+	Request Request
+
+	// Compile response
+	MainQuery string
+	Args      []interface{}
+	Err       error
+}{
+	{ // 1. Test arguments start index
+		Opts:      []Option{WithArgsIndex(3)},
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1*content!=a?"},
+		MainQuery: "select q.id from v_test q where q.id = $3 and q.content != $4",
+		Args:      []interface{}{1, "a"},
+	},
+	{ // 2. Test arguments start index with scopes and search
+		Opts:      []Option{WithArgsIndex(5)},
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1?", Search: "content~~smth", Scopes: []Scope{{Column: "tenant_id", Operator: "==", Value: 7}}},
+		MainQuery: "select q.id from v_test q where q.tenant_id = $5 and ((lower(q.content::text) like $6) and q.id = $7)",
+		Args:      []interface{}{7, "%smth%", 1},
+	},
+	{ // 3. Test question placeholders in PostgreSQL dialect
+		Opts:      []Option{WithPlaceholders(PlaceholderQuestion)},
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1*count!=2?"},
+		MainQuery: "select q.id from v_test q where q.id = ? and q.count != ?",
+		Args:      []interface{}{1, 2},
+	},
+	{ // 4. Test named placeholders skip NULL conditions
+		Opts:      []Option{WithPlaceholders(PlaceholderNamed), WithArgsIndex(2)},
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?content==null*ID==1?"},
+		MainQuery: "select q.id from v_test q where q.content is null and q.id = :p2",
+		Args:      []interface{}{sql.Named("p2", 1)},
+	},
+	{ // 5. Test @p placeholders of expanded array
+		Opts:      []Option{WithDialect(MySQL), WithPlaceholders(PlaceholderAtP), WithArgsIndex(2)},
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1,2*count>3?"},
+		MainQuery: "select q.id from v_test q where q.id in (@p2, @p3) and q.count > @p4",
+		Args:      []interface{}{1, 2, 3},
+	},
+	{ // 6. Test dollar placeholders in MySQL dialect
+		Opts:      []Option{WithDialect(MySQL), WithPlaceholders(PlaceholderDollar)},
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1?"},
+		MainQuery: "select q.id from v_test q where q.id = $1",
+		Args:      []interface{}{1},
+	},
+	{ // 7. Test question placeholders skip NULL conditions
+		Opts:      []Option{WithPlaceholders(PlaceholderQuestion)},
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==null*count==1?"},
+		MainQuery: "select q.id from v_test q where q.id is null and q.count = ?",
+		Args:      []interface{}{1},
+	},
+	{ // 8. Test dollar placeholders skip NULL conditions
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?(ID==null||content!=new)*count==1?"},
+		MainQuery: "select q.id from v_test q where (q.id is null or q.content != $1) and q.count = $2",
+		Args:      []interface{}{"new", 1},
+	},
+	{ // 9. Test ERROR invalid arguments start index
+		Opts:    []Option{WithArgsIndex(0)},
+		Request: Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1?"},
+		Err:     newError("Invalid arguments start index - 0"),
+	},
+}
+
+func TestPlaceholders(t *testing.T) {
+	for index, c := range testPlaceholdersCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			res, err := New(c.Opts...).Compile(context.Background(), c.Request)
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if res.MainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, res.MainQuery)
+				t.Fail()
+			}
+			if !reflect.DeepEqual(res.Args, c.Args) {
+				t.Errorf("expected args: %v, got: %v", c.Args, res.Args)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestArgEncoder(t *testing.T) {
+	encoder := func(values interface{}) interface{} {
+		return []interface{}{"encoded", values}
+	}
+
+	// legacy functions pass arrays as Go slices
+	mainQuery, _, args, err := Get(TestModel{}, "v_test", "ID?ID==1,2*content==a,b?", false, true)
+	if err != nil || mainQuery != "select q.id from v_test q where q.id = any($1) and q.content = any($2)" {
+		t.Errorf("expected mainQ: %v, got: %v (%v)", "select q.id from v_test q where q.id = any($1) and q.content = any($2)", mainQuery, err)
+	}
+	if !reflect.DeepEqual(args, []interface{}{[]int{1, 2}, []string{"a", "b"}}) {
+		t.Errorf("expected args: %v, got: %v", []interface{}{[]int{1, 2}, []string{"a", "b"}}, args)
+	}
+
+	res, err := New().Compile(context.Background(), Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1,2?"})
+	if err != nil || !reflect.DeepEqual(res.Args, []interface{}{[]int{1, 2}}) {
+		t.Errorf("expected args: %v, got: %v (%v)", []int{1, 2}, res.Args, err)
+	}
+
+	res, err = New(WithArgEncoder(encoder)).Compile(context.Background(), Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1,2?"})
+	if err != nil || !reflect.DeepEqual(res.Args, []interface{}{[]interface{}{"encoded", []int{1, 2}}}) {
+		t.Errorf("expected encoded args, got: %v (%v)", res.Args, err)
+	}
+
+	res, err = New(WithDialect(MySQL)).Compile(context.Background(), Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1,2?"})
+	if err != nil || !reflect.DeepEqual(res.Args, []interface{}{1, 2}) {
+		t.Errorf("expected expanded args: %v, got: %v (%v)", []interface{}{1, 2}, res.Args, err)
+	}
+}
@@ -0,0 +1,80 @@
+package compiler
+
+import (
+	"strings"
+)
+
+// relatedFields resolves field tokens of conditions on fields of related model
+type relatedFields struct {
+	r *relation
+}
+
+// fieldExpr returns sql expression of related model field, computed fields are not supported
+func (f relatedFields) fieldExpr(name string) string {
+	if _, ok := f.r.schema.exprs[name]; ok {
+		return ""
+	}
+	if column := f.r.schema.fieldsMap[name]; column != "" {
+		return f.r.alias + "." + column
+	}
+
+	return ""
+}
+
+// relation returns nil, as relations of related models are not read
+func (f relatedFields) relation(name string) *relation {
+	return nil
+}
+
+// hasRelationConditions reports whether conditions block contains relation condition
+func hasRelationConditions(conds string) bool {
+	for _, cond := range logicalOperatorsRegexp.Split(conds, -1) {
+		if isRelationCondition(cond) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formExistsCondition assembles EXISTS subquery of relation condition (items{sku==X*qty>2} or !items{...} for NOT EXISTS),
+// conditions of related model are compiled with the same arguments numbering
+func formExistsCondition(s fieldResolver, d Dialect, cond, logicalOperator string, condIndex *int) (string, []interface{}, *int, error) {
+	name, inner, err := decodeRelationBlock(cond)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	isNot := strings.HasPrefix(name, "!")
+	name = strings.TrimPrefix(name, "!")
+
+	r := s.relation(name)
+	if r == nil || !r.isMany {
+		return "", nil, nil, newError("Passed unexpected relation in condition - " + name)
+	}
+
+	query := "select 1 from " + r.target + " " + r.alias + " where " + r.alias + "." + r.foreignKey + " = q." + r.localKey
+	var args []interface{}
+	if len(inner) > 0 {
+		var preparedConds []string
+		preparedConds, args, condIndex, err = extractConditionsSet(relatedFields{r}, d, inner, false, condIndex)
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		innerConds := strings.TrimSpace(strings.Join(preparedConds, " "))
+		if strings.Contains(innerConds, " or ") {
+			innerConds = "(" + innerConds + ")"
+		}
+		query = query + " and " + innerConds
+	}
+
+	cond = "exists (" + query + ")"
+	if isNot {
+		cond = "not " + cond
+	}
+	if logicalOperator != "" {
+		return cond + " " + logicalBindings[logicalOperator], args, condIndex, nil
+	}
+
+	return cond, args, condIndex, nil
+}
@@ -0,0 +1,127 @@
+package compiler
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type TestOrderItemModel struct {
+	SKU *string `json:"sku,omitempty" sql:"sku"`
+	Qty *int    `json:"qty,omitempty" sql:"qty"`
+}
+
+type TestOrderModel struct {
+	ID     *int64                `json:"ID,omitempty" sql:"id"`
+	Status *string               `json:"status,omitempty" sql:"status"`
+	Items  []*TestOrderItemModel `json:"items,omitempty" join:"order_items,order_id"`
+}
+
+var testExistsCases = []struct {
+	// Compile params
+	Opts   []Option
+	Params string
+
+	// Compile response
+	MainQuery string
+	Args      []interface{}
+	Err       error
+}{
+	{ // 1. Test relation condition
+		Params:    "ID?items{sku==X*qty>2}?",
+		MainQuery: "select q.id from v_orders q where exists (select 1 from order_items q_items where q_items.order_id = q.id and q_items.sku = $1 and q_items.qty > $2)",
+		Args:      []interface{}{"X", 2},
+	},
+	{ // 2. Test arguments numbering of relation condition between conditions
+		Params:    "ID?status==new*items{qty>2}*ID>1?",
+		MainQuery: "select q.id from v_orders q where q.status = $1 and exists (select 1 from order_items q_items where q_items.order_id = q.id and q_items.qty > $2) and q.id > $3",
+		Args:      []interface{}{"new", 2, 1},
+	},
+	{ // 3. Test NOT EXISTS relation condition with OR operator in bracket block
+		Params:    "ID?(status==new||!items{sku==A||sku==B})?",
+		MainQuery: "select q.id from v_orders q where (q.status = $1 or not exists (select 1 from order_items q_items where q_items.order_id = q.id and (q_items.sku = $2 or q_items.sku = $3)))",
+		Args:      []interface{}{"new", "A", "B"},
+	},
+	{ // 4. Test relation condition without conditions
+		Params:    "ID?items{}?",
+		MainQuery: "select q.id from v_orders q where exists (select 1 from order_items q_items where q_items.order_id = q.id)",
+	},
+	{ // 5. Test relation condition with inline values
+		Opts:      []Option{WithArgs(false)},
+		Params:    "ID?items{qty>2}?",
+		MainQuery: "select q.id from v_orders q where exists (select 1 from order_items q_items where q_items.order_id = q.id and q_items.qty > 2)",
+	},
+	{ // 6. Test relation declared on registration
+		Params:    "ID?lines{sku==A}?",
+		MainQuery: "select q.id from v_orders q where exists (select 1 from order_lines q_lines where q_lines.order_ref = q.id and q_lines.sku = $1)",
+		Args:      []interface{}{"A"},
+	},
+	{ // 7. Test braces of condition value
+		Params:    "ID?status=={new}?",
+		MainQuery: "select q.id from v_orders q where q.status = $1",
+		Args:      []interface{}{"{new}"},
+	},
+	{ // 8. Test ERROR unexpected relation
+		Params: "ID?goods{sku==A}?",
+		Err:    newError("Passed unexpected relation in condition - goods"),
+	},
+	{ // 9. Test ERROR unexpected field of relation
+		Params: "ID?items{price>1}?",
+		Err:    newError("Passed unexpected field name in condition - price"),
+	},
+	{ // 10. Test ERROR relation condition without closing brace
+		Params: "ID?items{sku==A?",
+		Err:    newError("Passed unclosed relation block - items"),
+	},
+	{ // 11. Test ERROR field of one-to-many relation
+		Params: "ID?items.sku==A?",
+		Err:    newError("Passed unexpected field name in condition - items.sku"),
+	},
+	{ // 12. Test relation condition with spaces
+		Params:    "ID?status == new * items{sku == X * qty > 2}?",
+		MainQuery: "select q.id from v_orders q where q.status = $1 and exists (select 1 from order_items q_items where q_items.order_id = q.id and q_items.sku = $2 and q_items.qty > $3)",
+		Args:      []interface{}{"new", "X", 2},
+	},
+	{ // 13. Test ERROR relation condition in ClickHouse dialect
+		Opts:   []Option{WithDialect(ClickHouse)},
+		Params: "ID?items{qty>2}?",
+		Err:    newError("Passed unsupported relation condition in ClickHouse dialect"),
+	},
+	{ // 14. Test ERROR relation condition in having conditions
+		Params: "status,:count???status?items{qty>2}",
+		Err:    newError("Passed unexpected relation in condition - items"),
+	},
+}
+
+func TestExists(t *testing.T) {
+	s, err := Register(TestOrderModel{}, "v_orders", WithHasMany("lines", TestOrderItemModel{}, "order_lines", "id=order_ref"))
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+
+	for index, c := range testExistsCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			res, err := New(c.Opts...).Compile(context.Background(), Request{Model: s, Params: c.Params})
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if res.MainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, res.MainQuery)
+				t.Fail()
+			}
+			if c.Args != nil && !reflect.DeepEqual(res.Args, c.Args) {
+				t.Errorf("expected args: %v, got: %v", c.Args, res.Args)
+				t.Fail()
+			}
+		})
+	}
+}
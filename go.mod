@@ -1,5 +1,3 @@
 module github.com/ArtemYeremeev/SQaLice-compiler
 
 go 1.16
-
-require github.com/lib/pq v1.10.9 // indirect
@@ -0,0 +1,126 @@
+package compiler
+
+import (
+	"strings"
+)
+
+// formRelationSelection assembles subquery column of one-to-many relation selection (items{sku,qty?qty>2?sku,desc}),
+// which aggregates related rows into JSON array. Block of relation contains fields, conditions and order of related rows,
+// returns column with arguments and index of the next argument placeholder
+func formRelationSelection(s *Schema, d Dialect, token string, index int, withArgs bool) (string, []interface{}, int, error) {
+	name, contents, err := decodeRelationBlock(token)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	r := s.relation(name)
+	if r == nil || !r.isMany {
+		return "", nil, 0, newError("Passed unexpected relation in select - " + name)
+	}
+	related := relatedFields{r}
+
+	blocks := strings.Split(strings.ReplaceAll(contents, " ", ""), "?")
+	if len(blocks) > 3 {
+		return "", nil, 0, newError("Passed unexpected relation selection - " + name)
+	}
+
+	// fields
+	var fields []string
+	if blocks[0] == "" { // select all fields of related model
+		fields = sortMap(r.schema.fieldsMap)
+	} else {
+		fields = strings.Split(blocks[0], ",")
+	}
+	pairs := make([]string, 0, len(fields))
+	for _, f := range fields {
+		expr := related.fieldExpr(f)
+		if expr == "" {
+			return "", nil, 0, newError("Passed unexpected field name in relation selection - " + f)
+		}
+		pairs = append(pairs, "'"+f+"', "+expr)
+	}
+
+	// conditions
+	where := r.alias + "." + r.foreignKey + " = q." + r.localKey
+	var args []interface{}
+	if len(blocks) > 1 && blocks[1] != "" {
+		var condIndex *int
+		if withArgs {
+			condIndex = &index
+		}
+		var preparedConds []string
+		preparedConds, args, condIndex, err = extractConditionsSet(related, d, blocks[1], false, condIndex)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		if withArgs {
+			index = *condIndex
+		} else { // values are inlined into query
+			args = nil
+		}
+
+		conds := strings.TrimSpace(strings.Join(preparedConds, " "))
+		if strings.Contains(conds, " or ") {
+			conds = "(" + conds + ")"
+		}
+		where = where + " and " + conds
+	}
+
+	// order
+	orderBy := ""
+	if len(blocks) > 2 && blocks[2] != "" {
+		orderBy, err = relationOrder(related, d, name, blocks[2])
+		if err != nil {
+			return "", nil, 0, err
+		}
+	}
+
+	query := "(select coalesce(json_agg(json_build_object(" + strings.Join(pairs, ", ") + ")" + orderBy + "), '[]') from " +
+		r.target + " " + r.alias + " where " + where + ") as " + name
+
+	return query, args, index, nil
+}
+
+// relationOrder assembles ORDER BY clause of aggregated related rows, limit and offset of related rows are not supported
+func relationOrder(related relatedFields, d Dialect, name, rests string) (string, error) {
+	restsArr := strings.Split(rests, ",")
+	if len(restsArr) > 4 || (len(restsArr) > 2 && restsArr[2] != "") || (len(restsArr) > 3 && restsArr[3] != "") {
+		return "", newError("Passed unsupported limit of relation selection - " + name)
+	}
+
+	order := "asc"
+	if len(restsArr) > 1 && restsArr[1] != "" {
+		if restsArr[1] != "asc" && restsArr[1] != "desc" {
+			return "", newError("Unexpected selection order - " + restsArr[1])
+		}
+		order = restsArr[1]
+	}
+	if restsArr[0] == "" {
+		return "", nil
+	}
+
+	var exprs []string
+	for _, token := range strings.Split(restsArr[0], "|") {
+		field, fieldOrder, nulls, err := parseSortToken(token, order)
+		if err != nil {
+			return "", err
+		}
+		f := related.fieldExpr(field)
+		if f == "" {
+			return "", newError("Unexpected selection order field - " + field)
+		}
+		exprs = append(exprs, d.SortExpr(f, fieldOrder, nulls))
+	}
+
+	return " order by " + strings.Join(exprs, ", "), nil
+}
+
+// hasRelationSelections reports whether fields tokens contain relation selection
+func hasRelationSelections(tokens []string) bool {
+	for _, t := range tokens {
+		if isRelationBlock(strings.TrimSpace(t)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,114 @@
+package compiler
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+var testRelationSelectionCases = []struct {
+	// Compile params
+	Opts   []Option
+	Params string
+
+	// Compile response
+	MainQuery  string
+	CountQuery string
+	Args       []interface{}
+	Err        error
+}{
+	{ // 1. Test relation selection of all related fields
+		Params:    "ID,items{}??",
+		MainQuery: "select q.id, (select coalesce(json_agg(json_build_object('qty', q_items.qty, 'sku', q_items.sku)), '[]') from order_items q_items where q_items.order_id = q.id) as items from v_orders q",
+	},
+	{ // 2. Test relation selection with conditions and order of related rows
+		Params:    "ID,items{sku,qty?qty>2*sku!=X?qty,desc,,}??",
+		MainQuery: "select q.id, (select coalesce(json_agg(json_build_object('sku', q_items.sku, 'qty', q_items.qty) order by q_items.qty desc), '[]') from order_items q_items where q_items.order_id = q.id and q_items.qty > $1 and q_items.sku != $2) as items from v_orders q",
+		Args:      []interface{}{2, "X"},
+	},
+	{ // 3. Test arguments numbering of relation selection after conditions with count query
+		Opts:       []Option{WithCount(CountExact)},
+		Params:     "ID,lines{sku?qty>1||qty<0?}?status==new?",
+		MainQuery:  "select q.id, (select coalesce(json_agg(json_build_object('sku', q_lines.sku)), '[]') from order_lines q_lines where q_lines.order_ref = q.id and (q_lines.qty > $2 or q_lines.qty < $3)) as lines from v_orders q where q.status = $1",
+		CountQuery: "select count(*) from (select q.id, (select coalesce(json_agg(json_build_object('sku', q_lines.sku)), '[]') from order_lines q_lines where q_lines.order_ref = q.id and (q_lines.qty > $2 or q_lines.qty < $3)) as lines from v_orders q where q.status = $1) q",
+		Args:       []interface{}{"new", 1, 0},
+	},
+	{ // 4. Test relation selection with relation condition
+		Params:    "items{sku}?items{qty>2}?",
+		MainQuery: "select (select coalesce(json_agg(json_build_object('sku', q_items.sku)), '[]') from order_items q_items where q_items.order_id = q.id) as items from v_orders q where exists (select 1 from order_items q_items where q_items.order_id = q.id and q_items.qty > $1)",
+		Args:      []interface{}{2},
+	},
+	{ // 5. Test relation selection with inline values
+		Opts:      []Option{WithArgs(false)},
+		Params:    "items{sku?qty>2?}??",
+		MainQuery: "select (select coalesce(json_agg(json_build_object('sku', q_items.sku)), '[]') from order_items q_items where q_items.order_id = q.id and q_items.qty > 2) as items from v_orders q",
+	},
+	{ // 6. Test ERROR relation selection in MySQL dialect
+		Opts:   []Option{WithDialect(MySQL)},
+		Params: "ID,items{sku}??",
+		Err:    newError("Passed unsupported relation selection in MySQL dialect"),
+	},
+	{ // 7. Test ERROR unexpected relation in select
+		Params: "ID,orders{sku}??",
+		Err:    newError("Passed unexpected relation in select - orders"),
+	},
+	{ // 8. Test ERROR unexpected field of related model
+		Params: "ID,items{price}??",
+		Err:    newError("Passed unexpected field name in relation selection - price"),
+	},
+	{ // 9. Test ERROR limit of related rows
+		Params: "ID,items{sku??sku,asc,5,}??",
+		Err:    newError("Passed unsupported limit of relation selection - items"),
+	},
+	{ // 10. Test relation selection with spaces
+		Params:    "ID, items{sku, qty ? qty > 2 ?}??",
+		MainQuery: "select q.id, (select coalesce(json_agg(json_build_object('sku', q_items.sku, 'qty', q_items.qty)), '[]') from order_items q_items where q_items.order_id = q.id and q_items.qty > $1) as items from v_orders q",
+		Args:      []interface{}{2},
+	},
+	{ // 11. Test ERROR relation selection with positional placeholders
+		Opts:   []Option{WithPlaceholders(PlaceholderQuestion)},
+		Params: "ID,items{sku?qty>2?}?status==new?",
+		Err:    newError("Passed unsupported relation selection with positional placeholders"),
+	},
+	{ // 12. Test ERROR relation selection with grouping
+		Params: "status,items{sku}???status",
+		Err:    newError("Passed relation selection with grouping - items"),
+	},
+}
+
+func TestRelationSelection(t *testing.T) {
+	s, err := Register(TestOrderModel{}, "v_orders", WithHasMany("lines", TestOrderItemModel{}, "order_lines", "id=order_ref"))
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+
+	for index, c := range testRelationSelectionCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			res, err := New(c.Opts...).Compile(context.Background(), Request{Model: s, Params: c.Params})
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if res.MainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, res.MainQuery)
+				t.Fail()
+			}
+			if res.CountQuery != c.CountQuery {
+				t.Errorf("expected countQ: %v, got: %v", c.CountQuery, res.CountQuery)
+				t.Fail()
+			}
+			if c.Args != nil && !reflect.DeepEqual(res.Args, c.Args) {
+				t.Errorf("expected args: %v, got: %v", c.Args, res.Args)
+				t.Fail()
+			}
+		})
+	}
+}
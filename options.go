@@ -0,0 +1,220 @@
+package compiler
+
+import (
+	"context"
+	"strconv"
+)
+
+// Compiler compiles SQaLice requests with options, set once on creation
+type Compiler struct {
+	withArgs     bool
+	dialect      Dialect
+	placeholders PlaceholderStyle
+	argsIndex    int
+	argEncoder   ArgEncoder
+	countMode    CountMode
+	threshold    int64
+	limits       PageLimits
+	hooks        []Hook
+	nullArgs     bool // nil arguments of NULL conditions are kept for Get and Search
+}
+
+// Option configures compiler
+type Option func(*Compiler)
+
+// Hook is called before request compilation and may modify request (e.g. add scopes from context)
+// or reject it with error
+type Hook func(ctx context.Context, req *Request) error
+
+// Request describes SQaLice request to compile
+type Request struct {
+	Model  interface{} // model struct or registered *Schema
+	Target string      // target table or view, schema target is used if empty
+	Params string      // SQaLice query string
+	Search string      // search conditions
+	Scopes []Scope     // mandatory server-side predicates
+}
+
+// Result describes compiled SQL queries with arguments and named parts of main query,
+// which may be used to compose a wrapping query
+type Result struct {
+	MainQuery  string
+	CountQuery string
+	Args       []interface{}
+
+	CountArgs       []interface{} // arguments of count query, target name of statistics query in CountEstimated mode
+	ExactCountQuery string        // exact count query of CountEstimated mode
+	ExactCountArgs  []interface{} // arguments of exact count query, Args without arguments of cursor
+
+	countMode     CountMode
+	threshold     int64
+	isStatistics  bool // count query reads statistics of target
+	isSeekGrouped bool // cursor predicate is joined to Having
+
+	Distinct   string        // distinct clause of select list (distinct, distinct on (q.author))
+	Select     string        // select list (q.id, q.title), including total_count in CountWindow mode
+	SelectArgs []interface{} // arguments of relation selections, numbered after arguments of conditions
+	From       string        // target with alias and joins of referenced relations (v_tasks q)
+	Where      string        // conditions expression without WHERE keyword
+	WhereArgs  []interface{} // arguments of conditions expression
+	GroupBy    string        // group expression without GROUP BY keyword
+	Having     string        // group conditions expression without HAVING keyword
+	HavingArgs []interface{} // arguments of group conditions expression
+	Seek       string        // cursor predicate, joined to Where (or to Having of grouped selection) in main query only
+	SeekArgs   []interface{} // arguments of cursor predicate, the last ones in Args
+	OrderBy    string        // sort expression without ORDER BY keyword
+	Limit      *int
+	Offset     *int
+}
+
+// SQL assembles main query from named parts
+func (r *Result) SQL() string {
+	return r.assemble(r.Select, true)
+}
+
+// assemble joins query parts with passed select list, restrictions and cursor predicate are omitted if withRests is false
+func (r *Result) assemble(selectList string, withRests bool) string {
+	if r.Distinct != "" {
+		selectList = r.Distinct + " " + selectList
+	}
+	where, having := r.Where, r.Having
+	if withRests && r.Seek != "" { // select rows after cursor position
+		if r.isSeekGrouped {
+			having = joinSeekCondition(having, r.Seek)
+		} else {
+			where = joinSeekCondition(where, r.Seek)
+		}
+	}
+
+	query := "select " + selectList + " from " + r.From
+	if where != "" {
+		query = query + " where " + where
+	}
+	if r.GroupBy != "" {
+		query = query + " group by " + r.GroupBy
+	}
+	if having != "" {
+		query = query + " having " + having
+	}
+	if !withRests {
+		return query
+	}
+
+	if r.OrderBy != "" {
+		query = query + " order by " + r.OrderBy
+	}
+	if r.Limit != nil {
+		query = query + " limit " + strconv.Itoa(*r.Limit)
+	}
+	if r.Offset != nil {
+		query = query + " offset " + strconv.Itoa(*r.Offset)
+	}
+
+	return query
+}
+
+// New creates compiler with passed options. By default compiler passes values as arguments,
+// compiles PostgreSQL syntax, does not compile count query and does not restrict selection size
+func New(opts ...Option) *Compiler {
+	c := &Compiler{withArgs: true, dialect: PostgreSQL, argsIndex: 1}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithArgs sets whether condition values are passed as arguments or inlined into query
+func WithArgs(withArgs bool) Option {
+	return func(c *Compiler) {
+		c.withArgs = withArgs
+	}
+}
+
+// WithDialect sets SQL syntax of target database
+func WithDialect(d Dialect) Option {
+	return func(c *Compiler) {
+		if d != nil {
+			c.dialect = d
+		}
+	}
+}
+
+// WithPlaceholders sets format of arguments placeholders instead of dialect placeholders
+func WithPlaceholders(style PlaceholderStyle) Option {
+	return func(c *Compiler) {
+		c.placeholders = style
+	}
+}
+
+// WithArgsIndex sets index of the first argument placeholder, so compiled conditions
+// may be merged into parameterized query with preceding arguments
+func WithArgsIndex(index int) Option {
+	return func(c *Compiler) {
+		c.argsIndex = index
+	}
+}
+
+// WithArgEncoder sets array encoder of compiler, arrays are passed as Go slices by default (e.g. pqargs.Option for lib/pq)
+func WithArgEncoder(encoder ArgEncoder) Option {
+	return func(c *Compiler) {
+		c.argEncoder = encoder
+	}
+}
+
+// WithCount sets mode of counting result rows
+func WithCount(mode CountMode) Option {
+	return func(c *Compiler) {
+		c.countMode = mode
+	}
+}
+
+// WithEstimateThreshold sets rows count, below which estimated count is recounted with exact query
+func WithEstimateThreshold(threshold int64) Option {
+	return func(c *Compiler) {
+		c.threshold = threshold
+	}
+}
+
+// WithPageLimits sets selection restrictions of compiler
+func WithPageLimits(limits PageLimits) Option {
+	return func(c *Compiler) {
+		c.limits = limits
+	}
+}
+
+// WithHooks adds hooks called before request compilation
+func WithHooks(hooks ...Hook) Option {
+	return func(c *Compiler) {
+		c.hooks = append(c.hooks, hooks...)
+	}
+}
+
+// Compile builds SQL queries of request
+func (c *Compiler) Compile(ctx context.Context, req Request) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := c.limits.validate(); err != nil {
+		return nil, err
+	}
+	for _, hook := range c.hooks {
+		if err := hook(ctx, &req); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.compile(req)
+}
+
+// queryDialect returns dialect of compiler with configured placeholders and array encoder
+func (c *Compiler) queryDialect() (Dialect, error) {
+	if c.argsIndex < 1 {
+		return nil, newError("Invalid arguments start index - " + strconv.Itoa(c.argsIndex))
+	}
+	if c.placeholders == PlaceholderDefault && c.argsIndex == 1 && c.argEncoder == nil && !c.nullArgs {
+		return c.dialect, nil
+	}
+
+	return compilerDialect{Dialect: c.dialect, style: c.placeholders, start: c.argsIndex, encode: c.argEncoder, nullArgs: c.nullArgs}, nil
+}
@@ -0,0 +1,182 @@
+package compiler
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+type testTenantKey struct{}
+
+// testTenantHook adds tenant scope from context to request
+func testTenantHook(ctx context.Context, req *Request) error {
+	tenantID, ok := ctx.Value(testTenantKey{}).(int)
+	if !ok {
+		return errors.New("tenant not passed")
+	}
+
+	req.Scopes = append(req.Scopes, Scope{Column: "tenant_id", Operator: "==", Value: tenantID})
+	return nil
+}
+
+var testCompileCases = []struct {
+	// Compile params
+	Opts    []Option
+	Request Request
+	Ctx     context.Context
+
+	// Compile response
+	MainQuery  string
+	CountQuery string
+	Args       []interface{}
+	Err        error
+}{
+	{ // 1. Test default options
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1?"},
+		MainQuery: "select q.id from v_test q where q.id = $1",
+		Args:      []interface{}{1},
+	},
+	{ // 2. Test inline values with exact count
+		Opts:       []Option{WithArgs(false), WithCount(CountExact)},
+		Request:    Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1?"},
+		MainQuery:  "select q.id from v_test q where q.id = 1",
+		CountQuery: "select count(*) from (select 1 from v_test q where q.id = 1) q",
+	},
+	{ // 3. Test compiler page limits
+		Opts:      []Option{WithPageLimits(PageLimits{DefaultLimit: 10, MaxLimit: 50})},
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID??ID,asc,100,"},
+		MainQuery: "select q.id from v_test q order by q.id asc limit 50",
+	},
+	{ // 4. Test search request with scopes
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID??", Search: "content~~smth", Scopes: []Scope{{Column: "tenant_id", Operator: "==", Value: 5}}},
+		MainQuery: "select q.id from v_test q where q.tenant_id = $1 and ((lower(q.content::text) like $2))",
+		Args:      []interface{}{5, "%smth%"},
+	},
+	{ // 5. Test hook adding scope from context
+		Opts:      []Option{WithHooks(testTenantHook)},
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1?"},
+		Ctx:       context.WithValue(context.Background(), testTenantKey{}, 7),
+		MainQuery: "select q.id from v_test q where q.tenant_id = $1 and (q.id = $2)",
+		Args:      []interface{}{7, 1},
+	},
+	{ // 6. Test ERROR hook rejecting request
+		Opts:    []Option{WithHooks(testTenantHook)},
+		Request: Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID==1?"},
+		Err:     errors.New("tenant not passed"),
+	},
+	{ // 7. Test window count
+		Opts:      []Option{WithCount(CountWindow)},
+		Request:   Request{Model: TestModel{}, Target: "v_test", Params: "ID?ID>1?ID,asc,10,"},
+		MainQuery: "select q.id, count(*) over() as total_count from v_test q where q.id > $1 order by q.id asc limit 10",
+		Args:      []interface{}{1},
+	},
+	{ // 8. Test ERROR invalid compiler page limits
+		Opts:    []Option{WithPageLimits(PageLimits{MaxLimit: -1})},
+		Request: Request{Model: TestModel{}, Target: "v_test", Params: "ID??"},
+		Err:     newError("Invalid negative page limits"),
+	},
+	{ // 9. Test ERROR window count with distinct selection
+		Opts:    []Option{WithCount(CountWindow)},
+		Request: Request{Model: TestModel{}, Target: "v_test", Params: "distinct,content??"},
+		Err:     newError("Passed unsupported window count with distinct selection"),
+	},
+}
+
+func TestCompile(t *testing.T) {
+	for index, c := range testCompileCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			ctx := c.Ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			res, err := New(c.Opts...).Compile(ctx, c.Request)
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if res.MainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, res.MainQuery)
+				t.Fail()
+			}
+			if res.CountQuery != c.CountQuery {
+				t.Errorf("expected countQ: %v, got: %v", c.CountQuery, res.CountQuery)
+				t.Fail()
+			}
+			if c.Args != nil {
+				if len(res.Args) != len(c.Args) {
+					t.Errorf("expected args: %v, got: %v", c.Args, res.Args)
+					t.FailNow()
+				}
+				for i, v := range res.Args {
+					if c.Args[i] != v {
+						t.Errorf("expected arg: %v, got: %v", c.Args[i], v)
+						t.Fail()
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCompileCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := New().Compile(ctx, Request{Model: TestModel{}, Target: "v_test", Params: "ID??"})
+	if err != context.Canceled {
+		t.Errorf("expected err: %v, got: %v", context.Canceled, err)
+	}
+}
+
+func TestResultParts(t *testing.T) {
+	res, err := New(WithCount(CountExact)).Compile(context.Background(), Request{
+		Model:  TestModel{},
+		Target: "v_test",
+		Params: "ID,content?(ID==1||isBool==true)*count>2?ID,desc,10,20",
+	})
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+
+	if res.Select != "q.id, q.content" {
+		t.Errorf("expected select: %v, got: %v", "q.id, q.content", res.Select)
+	}
+	if res.From != "v_test q" {
+		t.Errorf("expected from: %v, got: %v", "v_test q", res.From)
+	}
+	if res.Where != "(q.id = $1 or q.is_bool = $2) and q.count > $3" {
+		t.Errorf("expected where: %v, got: %v", "(q.id = $1 or q.is_bool = $2) and q.count > $3", res.Where)
+	}
+	if len(res.WhereArgs) != 3 {
+		t.Errorf("expected where args: %v, got: %v", []interface{}{1, true, 2}, res.WhereArgs)
+	}
+	if res.OrderBy != "q.id desc" {
+		t.Errorf("expected order by: %v, got: %v", "q.id desc", res.OrderBy)
+	}
+	if res.Limit == nil || *res.Limit != 10 || res.Offset == nil || *res.Offset != 20 {
+		t.Errorf("expected limit and offset: %v, %v, got: %v, %v", 10, 20, res.Limit, res.Offset)
+	}
+
+	expected := "select q.id, q.content from v_test q where (q.id = $1 or q.is_bool = $2) and q.count > $3 order by q.id desc limit 10 offset 20"
+	if res.SQL() != expected || res.MainQuery != expected {
+		t.Errorf("expected mainQ: %v, got: %v (%v)", expected, res.SQL(), res.MainQuery)
+	}
+
+	// compose query with lock clause from named parts
+	res.OrderBy = ""
+	res.Limit = nil
+	res.Offset = nil
+	expected = "select q.id, q.content from v_test q where (q.id = $1 or q.is_bool = $2) and q.count > $3 for update"
+	if locked := res.SQL() + " for update"; locked != expected {
+		t.Errorf("expected query: %v, got: %v", expected, locked)
+	}
+}
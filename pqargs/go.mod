@@ -0,0 +1,10 @@
+module github.com/ArtemYeremeev/SQaLice-compiler/pqargs
+
+go 1.16
+
+require (
+	github.com/ArtemYeremeev/SQaLice-compiler v0.0.0
+	github.com/lib/pq v1.10.9
+)
+
+replace github.com/ArtemYeremeev/SQaLice-compiler => ../
@@ -0,0 +1,32 @@
+// Package pqargs adapts array arguments of compiled queries to github.com/lib/pq driver
+package pqargs
+
+import (
+	"reflect"
+
+	compiler "github.com/ArtemYeremeev/SQaLice-compiler"
+	"github.com/lib/pq"
+)
+
+// Encoder wraps array argument with pq.Array
+func Encoder(values interface{}) interface{} {
+	return pq.Array(values)
+}
+
+// Option returns compiler option, which encodes array arguments for lib/pq
+func Option() compiler.Option {
+	return compiler.WithArgEncoder(Encoder)
+}
+
+// Args wraps array arguments of Get and Search functions with pq.Array, other arguments are returned as is
+func Args(args []interface{}) []interface{} {
+	encoded := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		if v := reflect.ValueOf(arg); v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+			arg = pq.Array(arg)
+		}
+		encoded = append(encoded, arg)
+	}
+
+	return encoded
+}
@@ -0,0 +1,73 @@
+package pqargs
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	compiler "github.com/ArtemYeremeev/SQaLice-compiler"
+)
+
+type testModel struct {
+	ID      *int64  `json:"ID,omitempty" sql:"id"`
+	Content *string `json:"content,omitempty" sql:"content"`
+}
+
+func TestOption(t *testing.T) {
+	res, err := compiler.New(Option()).Compile(context.Background(), compiler.Request{
+		Model:  testModel{},
+		Target: "v_test",
+		Params: "ID?ID==1,2*content==a?",
+	})
+	if err != nil {
+		t.Errorf("expected err: %v, got: %v", nil, err)
+		t.FailNow()
+	}
+
+	expectedQuery := "select q.id from v_test q where q.id = any($1) and q.content = $2"
+	if res.MainQuery != expectedQuery {
+		t.Errorf("expected mainQ: %v, got: %v", expectedQuery, res.MainQuery)
+	}
+	if len(res.Args) != 2 {
+		t.Errorf("expected args count: %v, got: %v", 2, len(res.Args))
+		t.FailNow()
+	}
+
+	checkArray(t, res.Args[0], "{1,2}")
+	if res.Args[1] != "a" {
+		t.Errorf("expected arg: %v, got: %v", "a", res.Args[1])
+	}
+}
+
+func TestArgs(t *testing.T) {
+	_, _, args, err := compiler.Get(testModel{}, "v_test", "ID?ID==1,2*content==a,b*ID!=3?", false, true)
+	if err != nil {
+		t.Errorf("expected err: %v, got: %v", nil, err)
+		t.FailNow()
+	}
+
+	args = Args(args)
+	if len(args) != 3 {
+		t.Errorf("expected args count: %v, got: %v", 3, len(args))
+		t.FailNow()
+	}
+
+	checkArray(t, args[0], "{1,2}")
+	checkArray(t, args[1], `{"a","b"}`)
+	if args[2] != 3 {
+		t.Errorf("expected arg: %v, got: %v", 3, args[2])
+	}
+}
+
+// checkArray checks that argument is pq array with passed value
+func checkArray(t *testing.T, arg interface{}, expected string) {
+	valuer, ok := arg.(driver.Valuer)
+	if !ok {
+		t.Errorf("expected pq array arg, got: %T", arg)
+		return
+	}
+	value, err := valuer.Value()
+	if err != nil || value != expected {
+		t.Errorf("expected arg value: %v, got: %v (%v)", expected, value, err)
+	}
+}
@@ -7,7 +7,13 @@ import (
 	"strings"
 )
 
-var mathOperatorsList = []string{"==", "!=", "<=", "<", ">=", ">>", ">", "!!"}
+var mathOperatorsList = []string{"->>", "==", "!=", "<=", "<", ">=", ">>", ">", "!!"}
+
+// Precompiled expressions of query parser
+var (
+	logicalOperatorsRegexp = regexp.MustCompile("[*|]")
+	bracketRegexp          = regexp.MustCompile(`\((.*?)\)`)
+)
 
 // CondExpr describes structure of query condition
 type CondExpr struct {
@@ -24,10 +30,19 @@ func GetFieldsList(model interface{}, q string) (fieldsList []string, err error)
 		return nil, newError("Query string not passed")
 	}
 
-	// form fields map with formDinamicModel
-	fieldsMap := formDinamicModel(model)
+	s, err := formSchema(model)
+	if err != nil {
+		return nil, err
+	}
 
-	fieldsBlock := strings.Split(q, "?")[0]
+	queryBlocks, err := splitQueryBlocks(q)
+	if err != nil {
+		return nil, err
+	}
+	_, _, fieldsBlock, err := parseDistinct(queryBlocks[0])
+	if err != nil {
+		return nil, err
+	}
 	if fieldsBlock == "" { // if fieldsBlock is empty then request all fields
 		return nil, nil
 	}
@@ -35,7 +50,18 @@ func GetFieldsList(model interface{}, q string) (fieldsList []string, err error)
 
 	var sqlFields []string
 	for _, f := range jsonFields {
-		field := fieldsMap[f]
+		field := s.columnAlias(f) // column or alias of related model field
+		if name, fn := splitFieldFunc(f); isFieldFunc(fn) { // field function column alias
+			field = ""
+			if name == "" && fn == "count" {
+				field = fn
+			} else if alias := s.columnAlias(name); alias != "" {
+				field = alias + "_" + fn
+			}
+		}
+		if isRelationBlock(f) { // relation selection column is named by relation
+			field, _, _ = decodeRelationBlock(f)
+		}
 		if field == "" {
 			return nil, newError("Passed unexpected field name in select - " + f)
 		}
@@ -55,13 +81,16 @@ func GetConditionsList(model interface{}, q string, toDBFormat bool, isSearch bo
 	// form fields map with formDinamicModel, if its necessary
 	var fieldsMap map[string]string
 	if toDBFormat {
-		fieldsMap = formDinamicModel(model)
+		fieldsMap, err = formDinamicModel(model)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// handle searchQuery conditions
 	if isSearch {
 		var respConds []*CondExpr
-		for _, cond := range regexp.MustCompile("[*|]").Split(q, -1) {
+		for _, cond := range logicalOperatorsRegexp.Split(q, -1) {
 			if cond == "" {
 				continue
 			}
@@ -77,15 +106,19 @@ func GetConditionsList(model interface{}, q string, toDBFormat bool, isSearch bo
 		return respConds, nil
 	}
 
-	condsBlock := strings.Split(q, "?")[1]
+	queryBlocks, err := splitQueryBlocks(q)
+	if err != nil {
+		return nil, err
+	}
+	condsBlock := queryBlocks[1]
 	if condsBlock == "" { // if condsBlock is empty then request conds not passed
 		return nil, nil
 	}
 
-	condsArray := regexp.MustCompile("[*|]").Split(condsBlock, -1) // split condsBlock by logicalOperators list
+	condsArray := logicalOperatorsRegexp.Split(condsBlock, -1) // split condsBlock by logicalOperators list
 	var respArray []*CondExpr
 	for _, cond := range condsArray {
-		if cond == "" {
+		if cond == "" || isRelationCondition(cond) { // relation conditions are not conditions of model fields
 			continue
 		}
 
@@ -112,17 +145,24 @@ func GetConditionByName(model interface{}, q string, fieldName string, toDBForma
 	// form fields map with formDinamicModel, if its necessary
 	var fieldsMap map[string]string
 	if toDBFormat {
-		fieldsMap = formDinamicModel(model)
+		fieldsMap, err = formDinamicModel(model)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	condsBlock := strings.Split(q, "?")[1]
+	queryBlocks, err := splitQueryBlocks(q)
+	if err != nil {
+		return nil, err
+	}
+	condsBlock := queryBlocks[1]
 	if condsBlock == "" { // if condsBlock is empty then request conds not passed
 		return nil, nil
 	}
 
-	condsArray := regexp.MustCompile("[*|]").Split(condsBlock, -1) // split condsBlock by logicalOperators list
+	condsArray := logicalOperatorsRegexp.Split(condsBlock, -1) // split condsBlock by logicalOperators list
 	for _, cond := range condsArray {
-		if cond == "" {
+		if cond == "" || isRelationCondition(cond) { // relation conditions are not conditions of model fields
 			continue
 		}
 
@@ -155,9 +195,16 @@ func GetSortField(model interface{}, q string) (fields []string, err error) {
 	}
 
 	// form fields map with formDinamicModel
-	fieldsMap := formDinamicModel(model)
+	fieldsMap, err := formDinamicModel(model)
+	if err != nil {
+		return nil, err
+	}
 
-	restsBlock := strings.Split(q, "?")[2]
+	queryBlocks, err := splitQueryBlocks(q)
+	if err != nil {
+		return nil, err
+	}
+	restsBlock := queryBlocks[2]
 	if restsBlock == "" { // if condsBlock is empty then sort field not passed
 		return nil, nil
 	}
@@ -167,7 +214,11 @@ func GetSortField(model interface{}, q string) (fields []string, err error) {
 	}
 
 	var respFields []string
-	for _, f := range strings.Split(flds, "|") {
+	for _, token := range strings.Split(flds, "|") {
+		f, _, _, err := parseSortToken(token, "")
+		if err != nil {
+			return nil, err
+		}
 		sortField := fieldsMap[f]
 		if sortField == "" {
 			return nil, newError("Passed unexpected selection order field - " + f)
@@ -179,12 +230,74 @@ func GetSortField(model interface{}, q string) (fields []string, err error) {
 	return respFields, nil
 }
 
+// SortSpec describes selection sort field
+type SortSpec struct {
+	Field  string // API field name
+	Column string // sql column of field
+	Order  string // asc or desc
+	Nulls  string // first, last or empty for database default placement
+}
+
+// GetSortSpecs returns selection sort fields with their order and nulls placement from query.
+// Fields without own order (createdAt:desc) use selection order of restrictions block
+func GetSortSpecs(model interface{}, q string) (specs []SortSpec, err error) {
+	if q == "" {
+		return nil, newError("Query string not passed")
+	}
+
+	// form fields map with formDinamicModel
+	fieldsMap, err := formDinamicModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	queryBlocks, err := splitQueryBlocks(q)
+	if err != nil {
+		return nil, err
+	}
+	restsBlock := queryBlocks[2]
+	if restsBlock == "" { // if restsBlock is empty then sort fields not passed
+		return nil, nil
+	}
+	rests := strings.Split(restsBlock, ",")
+	if rests[0] == "" {
+		return nil, nil // if field is empty then sort fields not passed
+	}
+
+	order := "asc"
+	if len(rests) > 1 && rests[1] != "" {
+		if rests[1] != "asc" && rests[1] != "desc" {
+			return nil, newError("Passed unexpected selection order - " + rests[1])
+		}
+		order = rests[1]
+	}
+
+	for _, token := range strings.Split(rests[0], "|") {
+		f, fieldOrder, nulls, err := parseSortToken(token, order)
+		if err != nil {
+			return nil, err
+		}
+		column := fieldsMap[f]
+		if column == "" {
+			return nil, newError("Passed unexpected selection order field - " + f)
+		}
+
+		specs = append(specs, SortSpec{Field: f, Column: column, Order: fieldOrder, Nulls: nulls})
+	}
+
+	return specs, nil
+}
+
 // GetSortOrder returns selection order from query
 func GetSortOrder(q string) (order string, err error) {
 	if q == "" {
 		return "", newError("Query string not passed")
 	}
-	restsBlock := strings.Split(q, "?")[2]
+	queryBlocks, err := splitQueryBlocks(q)
+	if err != nil {
+		return "", err
+	}
+	restsBlock := queryBlocks[2]
 	if restsBlock == "" { // if condsBlock is empty then sort order not passed
 		return "", nil
 	}
@@ -203,15 +316,27 @@ func GetSortOrder(q string) (order string, err error) {
 
 // GetLimit returns selection limit from query
 func GetLimit(q string) (limit *int, err error) {
+	return PageLimits{}.Limit(q)
+}
+
+// Limit returns selection limit from query with page limits applied
+func (limits PageLimits) Limit(q string) (limit *int, err error) {
 	if q == "" {
 		return nil, newError("Query string not passed")
 	}
 
-	restsBlock := strings.Split(q, "?")[2]
+	queryBlocks, err := splitQueryBlocks(q)
+	if err != nil {
+		return nil, err
+	}
+	restsBlock := queryBlocks[2]
 	if restsBlock == "" { // if condsBlock is empty then limit not passed
-		return nil, nil
+		return applyPageLimit(nil, limits), nil
 	}
 	l := strings.Split(restsBlock, ",")[2]
+	if l == "" {
+		return applyPageLimit(nil, limits), nil // if limit is empty then limit not passed
+	}
 
 	respLimit, err := strconv.Atoi(l)
 	if err != nil {
@@ -221,16 +346,25 @@ func GetLimit(q string) (limit *int, err error) {
 		return nil, newError("Invalid negative selection limit - " + l)
 	}
 
-	return &respLimit, nil
+	return applyPageLimit(&respLimit, limits), nil
 }
 
 // GetOffset returns selection offset from query
 func GetOffset(q string) (limit *int, err error) {
+	return PageLimits{}.Offset(q)
+}
+
+// Offset returns selection offset from query, checked against page limits
+func (limits PageLimits) Offset(q string) (limit *int, err error) {
 	if q == "" {
 		return nil, newError("Query string not passed")
 	}
 
-	restsBlock := strings.Split(q, "?")[2]
+	queryBlocks, err := splitQueryBlocks(q)
+	if err != nil {
+		return nil, err
+	}
+	restsBlock := queryBlocks[2]
 	if restsBlock == "" { // if condsBlock is empty then offset not passed
 		return nil, nil
 	}
@@ -247,6 +381,9 @@ func GetOffset(q string) (limit *int, err error) {
 	if respOffset < 0 {
 		return nil, newError("Invalid negative selection offset - " + o)
 	}
+	if err := checkPageOffset(respOffset, limits); err != nil {
+		return nil, err
+	}
 
 	return &respOffset, nil
 }
@@ -257,10 +394,16 @@ func AddQueryFieldsToSelect(model interface{}, query string, fieldsArray []strin
 	if query == "" {
 		return query, newError("Passed empty query for forming fields block")
 	}
-	queryBlocks := strings.Split(query, "?")
+	queryBlocks, err := splitQueryBlocks(query)
+	if err != nil {
+		return query, err
+	}
 
 	// form fields map with formDinamicModel
-	fieldsMap := formDinamicModel(model)
+	fieldsMap, err := formDinamicModel(model)
+	if err != nil {
+		return query, err
+	}
 
 	var selectBlock []string
 	// If fieldsMap passed, check if passed new fiedls correct
@@ -276,12 +419,16 @@ func AddQueryFieldsToSelect(model interface{}, query string, fieldsArray []strin
 	}
 
 	if isDeleteCurrent {
-		queryBlocks[0] = strings.Join(selectBlock, ",")
+		fields := strings.Join(selectBlock, ",")
+		if isDistinct, on, _, _ := parseDistinct(queryBlocks[0]); isDistinct || on != nil { // keep distinct selection
+			fields = strings.SplitN(queryBlocks[0], ",", 2)[0] + "," + fields
+		}
+		queryBlocks[0] = fields
 	} else {
 		queryBlocks[0] = queryBlocks[0] + "," + strings.Join(selectBlock, ",")
 	}
 
-	return strings.Join(queryBlocks, "?"), nil
+	return joinQueryBlocks(queryBlocks)
 }
 
 // AddQueryConditions adds conditions to query conditions list with AND separators
@@ -294,7 +441,10 @@ func AddQueryConditions(query string, conds []CondExpr, isDeleteCurrent, isLeadi
 	if conds == nil {
 		return query, nil
 	}
-	queryBlocks := strings.Split(query, "?")
+	queryBlocks, err := splitQueryBlocks(query)
+	if err != nil {
+		return query, err
+	}
 
 	if isDeleteCurrent {
 		queryBlocks[1] = ""
@@ -342,7 +492,7 @@ func AddQueryConditions(query string, conds []CondExpr, isDeleteCurrent, isLeadi
 		}
 	}
 
-	return strings.Join(queryBlocks, "?"), nil
+	return joinQueryBlocks(queryBlocks)
 }
 
 // ReplaceQueryCondition replaces query condition by fieldName
@@ -351,10 +501,7 @@ func ReplaceQueryCondition(model interface{}, query string, newCond CondExpr) (s
 		return query, newError("Passed empty query for changing condition")
 	}
 
-	// form fields map with formDinamicModel
-	fieldsMap := formDinamicModel(model)
-
-	oldCond, err := GetConditionByName(fieldsMap, query, newCond.FieldName, false)
+	oldCond, err := GetConditionByName(model, query, newCond.FieldName, false)
 	if err != nil {
 		return "", newError("Condition with passed name " + newCond.FieldName + " not found")
 	}
@@ -376,8 +523,12 @@ func ReplaceQueryCondition(model interface{}, query string, newCond CondExpr) (s
 		newCondString = newCond.FieldName + newCond.Operator + fmt.Sprintf("%v", newCond.Value)
 	}
 
+	query, err = encodeRelationBlocks(query) // contents of relation blocks are not replaced
+	if err != nil {
+		return "", err
+	}
 	replacer := strings.NewReplacer(oldCondString, newCondString)
-	return replacer.Replace(query), nil
+	return decodeRelationBlocks(replacer.Replace(query))
 }
 
 // DeleteQueryCondition prunes condition from query by fieldname
@@ -386,10 +537,7 @@ func DeleteQueryCondition(model interface{}, query, condName string) (string, er
 		return query, newError("Passed empty query for condition prune")
 	}
 
-	// form fields map with formDinamicModel
-	fieldsMap := formDinamicModel(model)
-
-	c, _ := GetConditionByName(fieldsMap, query, condName, false)
+	c, _ := GetConditionByName(model, query, condName, false)
 	if c == nil { // If condition with passed name not found, exit
 		return query, nil
 	}
@@ -401,6 +549,11 @@ func DeleteQueryCondition(model interface{}, query, condName string) (string, er
 		condString = c.FieldName + c.Operator + fmt.Sprintf("%v", c.Value)
 	}
 
+	query, err := encodeRelationBlocks(query) // contents of relation blocks are not pruned
+	if err != nil {
+		return "", err
+	}
+
 	// Prune condition
 	for k := range logicalBindings {
 		query = strings.Replace(query, k + condString, "", 1)
@@ -413,7 +566,7 @@ func DeleteQueryCondition(model interface{}, query, condName string) (string, er
 		query = strings.Replace(query, k + "?", "?", 1)
 	}
 
-	return query, nil
+	return decodeRelationBlocks(query)
 }
 
 // AddQueryRestrictions adds restrictions to query restrictions block instead of current
@@ -422,7 +575,10 @@ func AddQueryRestrictions(query string, sortFields string, sortOrder string, lim
 	if query == "" {
 		return query, newError("Passed empty query for forming restrictions block")
 	}
-	queryBlocks := strings.Split(query, "?")
+	queryBlocks, err := splitQueryBlocks(query)
+	if err != nil {
+		return query, err
+	}
 
 	// If rests block is empty, imitate block structure
 	var currentRests []string
@@ -450,12 +606,16 @@ func AddQueryRestrictions(query string, sortFields string, sortOrder string, lim
 	}
 	queryBlocks[2] = strings.Join(currentRests, ",")
 
-	return strings.Join(queryBlocks, "?"), nil
+	return joinQueryBlocks(queryBlocks)
 }
 
 func extractQueryCondition(fieldsMap map[string]string, cond string, toDBFormat bool) (condExpr *CondExpr, err error) {
 	var op string // get condition operator
 	for _, o := range mathOperatorsList {
+		if o == "->>" && strings.Contains(cond, o) { // INCLUDES contains characters of other operators
+			op = o
+			break
+		}
 		if strings.Contains(cond, o) {
 			op = o
 		}
@@ -473,7 +633,7 @@ func extractQueryCondition(fieldsMap map[string]string, cond string, toDBFormat
 	}
 
 	isBracket := false // handle bracket condition
-	bracketCond := regexp.MustCompile(`\((.*?)\)`).FindAllString(cond, -1)
+	bracketCond := bracketRegexp.FindAllString(cond, -1)
 	if bracketCond != nil {
 		cond = strings.Trim(cond, "(")
 		cond = strings.Trim(cond, ")")
@@ -503,3 +663,23 @@ func extractQueryCondition(fieldsMap map[string]string, cond string, toDBFormat
 		IsBracket: isBracket,
 	}, nil
 }
+
+// splitQueryBlocks splits query into blocks, contents of relation blocks (items{sku?qty>2?sku,desc}) are encoded
+func splitQueryBlocks(q string) ([]string, error) {
+	q, err := encodeRelationBlocks(q)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Split(q, "?"), nil
+}
+
+// joinQueryBlocks joins query blocks into query string with decoded contents of relation blocks
+func joinQueryBlocks(queryBlocks []string) (string, error) {
+	return decodeRelationBlocks(strings.Join(queryBlocks, "?"))
+}
+
+// isRelationCondition reports whether condition is a relation condition (items{sku==X} or (!items{...}))
+func isRelationCondition(cond string) bool {
+	return isRelationBlock(strings.Trim(cond, "()"))
+}
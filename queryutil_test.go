@@ -8,6 +8,7 @@ import (
 
 var testGetFieldsListCases = []struct {
 	// Query params
+	Model interface{} // TestModel by default
 	Query string
 	// Response
 	FieldsList []string
@@ -29,12 +30,30 @@ var testGetFieldsListCases = []struct {
 		Query:      "??",
 		FieldsList: nil,
 	},
-	{ // 5. Test ERROR empty query
+	{ // 5. Test query with distinct on selection
+		Query:      "distinct(content),ID,content??",
+		FieldsList: []string{"id", "content"},
+	},
+	{ // 6. Test query with aggregate functions
+		Query:      "content,count:sum,:count???content",
+		FieldsList: []string{"content", "count_sum", "count"},
+	},
+	{ // 7. Test query with related model fields
+		Model:      TestBookModel{},
+		Query:      "ID,author.name,author.createdAt:max??",
+		FieldsList: []string{"id", "author_name", "author_created_at_max"},
+	},
+	{ // 8. Test query with relation selection
+		Model:      TestOrderModel{},
+		Query:      "ID,items{sku?qty>2?sku,desc}?status==new?ID,asc,10,0",
+		FieldsList: []string{"id", "items"},
+	},
+	{ // 9. Test ERROR empty query
 		Query:      "",
 		FieldsList: nil,
 		Err:        newError("Query string not passed"),
 	},
-	{ // 6. Test ERROR unexpected fieldName in query select block
+	{ // 10. Test ERROR unexpected fieldName in query select block
 		Query:      "randomField??",
 		FieldsList: nil,
 		Err:        newError("Passed unexpected field name in select - randomField"),
@@ -44,7 +63,11 @@ var testGetFieldsListCases = []struct {
 func TestGetFieldsList(t *testing.T) {
 	for index, c := range testGetFieldsListCases {
 		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
-			fieldsList, err := GetFieldsList(TestModel{}, c.Query)
+			model := c.Model
+			if model == nil {
+				model = TestModel{}
+			}
+			fieldsList, err := GetFieldsList(model, c.Query)
 			if err != nil && err.Error() != c.Err.Error() {
 				t.Errorf("expected err: %v, got: %v", c.Err, err)
 				t.FailNow()
@@ -74,8 +97,9 @@ func isSlicesEqual(a, b []string) bool {
 
 var testGetConditionsListCases = []struct {
 	// Query params
-	Query    string
-	IsSearch bool
+	Query         string
+	IsSearch      bool
+	IsQueryFormat bool // conditions are returned in query format
 	// Response
 	CondExprsList []*CondExpr
 	Err           error
@@ -120,16 +144,36 @@ var testGetConditionsListCases = []struct {
 		IsSearch: true,
 		Err:      newError("Unsupported searchQuery format"),
 	},
+	{ // 8. Test query with relation selection
+		Query:         "ID,items{sku?qty>2?sku,desc}?ID==1?ID,asc,10,0",
+		IsSearch:      false,
+		CondExprsList: []*CondExpr{{FieldName: "id", Operator: "=", Value: "1", IsBracket: false}},
+	},
+	{ // 9. Test relation conditions are skipped
+		Query:         "ID?items{sku==X*qty>2}*content==new*(!items{qty<1})?",
+		IsSearch:      false,
+		CondExprsList: []*CondExpr{{FieldName: "content", Operator: "=", Value: "new", IsBracket: false}},
+	},
+	{ // 10. Test relation conditions are skipped in query format
+		Query:         "ID?items{sku==X*qty>2}*content==new?",
+		IsSearch:      false,
+		IsQueryFormat: true,
+		CondExprsList: []*CondExpr{{FieldName: "content", Operator: "==", Value: "new", IsBracket: false}},
+	},
 }
 
 func TestGetConditionsList(t *testing.T) {
 	for index, c := range testGetConditionsListCases {
 		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
-			condsList, err := GetConditionsList(TestModel{}, c.Query, true, c.IsSearch)
+			condsList, err := GetConditionsList(TestModel{}, c.Query, !c.IsQueryFormat, c.IsSearch)
 			if err != nil && c.Err.Error() != err.Error() {
 				t.Errorf("expected err: %v, got: %v", c.Err, err)
 				t.FailNow()
 			}
+			if err == nil && len(condsList) != len(c.CondExprsList) {
+				t.Errorf("expected conditions: %v, got: %v", len(c.CondExprsList), len(condsList))
+				t.FailNow()
+			}
 
 			for i, cond := range condsList {
 				if cond == nil {
@@ -200,6 +244,16 @@ var testGetConditionByNameCases = []struct {
 		CondExpr:  nil,
 		Err:       newError("Unsupported operator in condition"),
 	},
+	{ // 7. Test query with relation selection
+		Query:     "ID,items{sku?ID==2?sku,desc}?ID==1?",
+		FieldName: "ID",
+		CondExpr:  &CondExpr{FieldName: "id", Operator: "=", Value: "1", IsBracket: false},
+	},
+	{ // 8. Test relation condition is skipped
+		Query:     "ID?items{ID==2}*ID==1?",
+		FieldName: "ID",
+		CondExpr:  &CondExpr{FieldName: "id", Operator: "=", Value: "1", IsBracket: false},
+	},
 }
 
 func TestGetConditionByName(t *testing.T) {
@@ -268,6 +322,14 @@ var testGetRestsCases = []struct {
 		Offset: 0,
 		Err:    newError(""),
 	},
+	{ // 3. Test query with relation selection
+		Query:  "ID,items{sku?qty>2?sku,desc}?ID==1?ID,asc,10,0",
+		Fields: []string{"id"},
+		Order:  "asc",
+		Limit:  10,
+		Offset: 0,
+		Err:    newError(""),
+	},
 }
 
 func TestGetSortField(t *testing.T) {
@@ -294,6 +356,64 @@ func TestGetSortField(t *testing.T) {
 	}
 }
 
+var testGetSortSpecsCases = []struct {
+	Query string
+	Specs []SortSpec
+	Err   error
+}{
+	{ // 1. Test fields with block order
+		Query: "??ID|isBool,desc,10,",
+		Specs: []SortSpec{{Field: "ID", Column: "id", Order: "desc"}, {Field: "isBool", Column: "is_bool", Order: "desc"}},
+	},
+	{ // 2. Test fields with own order and nulls placement
+		Query: "??content:desc:nullslast|ID,,10,",
+		Specs: []SortSpec{{Field: "content", Column: "content", Order: "desc", Nulls: "last"}, {Field: "ID", Column: "id", Order: "asc"}},
+	},
+	{ // 3. Test empty rests block
+		Query: "ID??",
+	},
+	{ // 4. Test ERROR unexpected nulls placement
+		Query: "??ID:nullsmiddle,,,",
+		Err:   newError("Unexpected selection order - nullsmiddle"),
+	},
+	{ // 5. Test ERROR unexpected field
+		Query: "??title:desc,,,",
+		Err:   newError("Passed unexpected selection order field - title"),
+	},
+	{ // 6. Test query with relation selection
+		Query: "ID,items{sku?qty>2?sku,desc}??ID,desc,10,",
+		Specs: []SortSpec{{Field: "ID", Column: "id", Order: "desc"}},
+	},
+}
+
+func TestGetSortSpecs(t *testing.T) {
+	for index, c := range testGetSortSpecsCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			specs, err := GetSortSpecs(TestModel{}, c.Query)
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if len(specs) != len(c.Specs) {
+				t.Errorf("Expected sort specs: %v, got: %v", c.Specs, specs)
+				t.FailNow()
+			}
+			for i, s := range specs {
+				if s != c.Specs[i] {
+					t.Errorf("Expected sort spec: %v, got: %v", c.Specs[i], s)
+				}
+			}
+		})
+	}
+}
+
 func TestGetSortOrder(t *testing.T) {
 	for index, c := range testGetRestsCases {
 		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
@@ -358,6 +478,39 @@ func TestGetOffset(t *testing.T) {
 	}
 }
 
+func TestPageLimitsLimit(t *testing.T) {
+	limits := PageLimits{DefaultLimit: 20, MaxLimit: 100, MaxOffset: 1000}
+
+	cases := []struct {
+		Query string
+		Limit int
+	}{
+		{Query: "??", Limit: 20},
+		{Query: "??ID,asc,,", Limit: 20},
+		{Query: "??ID,asc,50,", Limit: 50},
+		{Query: "??ID,asc,500,", Limit: 100},
+	}
+	for index, c := range cases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			limit, err := limits.Limit(c.Query)
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if limit == nil || *limit != c.Limit {
+				t.Errorf("Expected limit: %v, got: %v", c.Limit, limit)
+				t.FailNow()
+			}
+		})
+	}
+
+	_, err := limits.Offset("??ID,asc,10,1001")
+	if err == nil || err.Error() != newError("Selection offset exceeds maximum - 1001").Error() {
+		t.Errorf("expected err: %v, got: %v", newError("Selection offset exceeds maximum - 1001"), err)
+	}
+}
+
 var testAddQueryFieldsToSelectCases = []struct {
 	// Params
 	Query           string
@@ -385,6 +538,12 @@ var testAddQueryFieldsToSelectCases = []struct {
 		isDeleteCurrent: true,
 		RespQuery:       "ID,count??",
 	},
+	{ // 4. Test adding fields to select block with relation selection
+		Query:           "items{sku?qty>2?sku,desc}??ID,asc,10,0",
+		NewFields:       []string{"ID"},
+		isDeleteCurrent: false,
+		RespQuery:       "items{sku?qty>2?sku,desc},ID??ID,asc,10,0",
+	},
 }
 
 func TestAddQueryFieldsToSelect(t *testing.T) {
@@ -525,6 +684,20 @@ var testAddQueryConditionsCases = []struct {
 		IsLeading:       true,
 		RespQuery:       "ID?(name!=smth)*ID==1*isBool==true?ID,asc,10,0",
 	},
+	{ // 8. Test adding condition to query with relation selection
+		Query: "ID,items{sku?qty>2?sku,desc}?content==new?ID,asc,10,0",
+		NewConds: []CondExpr{
+			{
+				FieldName: "ID",
+				Operator:  "==",
+				Value:     1,
+				IsBracket: false,
+			},
+		},
+		IsDeleteCurrent: false,
+		IsLeading:       false,
+		RespQuery:       "ID,items{sku?qty>2?sku,desc}?content==new*ID==1?ID,asc,10,0",
+	},
 }
 
 func TestAddQueryConditions(t *testing.T) {
@@ -581,6 +754,26 @@ var testReplaceQueryConditionCases = []struct {
 		},
 		RespQuery: "ID?isBool==true?ID,asc,10,0",
 	},
+	{ // 4. Test replace condition in query with relation selection
+		Query: "ID,items{sku?count==1?sku,desc}?count==1?",
+		NewCond: CondExpr{
+			FieldName: "count",
+			Operator:  "!=",
+			Value:     5,
+			IsBracket: false,
+		},
+		RespQuery: "ID,items{sku?count==1?sku,desc}?count!=5?",
+	},
+	{ // 5. Test replace condition in query with relation condition
+		Query: "ID?items{count==1}*count==1?",
+		NewCond: CondExpr{
+			FieldName: "count",
+			Operator:  "!=",
+			Value:     5,
+			IsBracket: false,
+		},
+		RespQuery: "ID?items{count==1}*count!=5?",
+	},
 }
 
 func TestReplaceQueryCondition(t *testing.T) {
@@ -632,6 +825,21 @@ var testDeleteQueryConditionCases = []struct {
 		CondName:  "name",
 		RespQuery: "?ID==1?",
 	},
+	{ // 6. Test condition delete from query with relation selection
+		Query:     "ID,items{sku?count==1?sku,desc}?ID==1*count==1?",
+		CondName:  "count",
+		RespQuery: "ID,items{sku?count==1?sku,desc}?ID==1?",
+	},
+	{ // 7. Test condition delete from query with relation condition
+		Query:     "?ID==1*items{count==1}*count==1?",
+		CondName:  "count",
+		RespQuery: "?ID==1*items{count==1}?",
+	},
+	{ // 8. Test relation condition is not deleted
+		Query:     "?items{count==1}?",
+		CondName:  "items",
+		RespQuery: "?items{count==1}?",
+	},
 }
 
 func TestDeleteQueryCondition(t *testing.T) {
@@ -694,6 +902,12 @@ var testAddQueryRestrictionsCases = []struct {
 		Offset:    "2",
 		RespQuery: "ID,count??count,asc,10,2",
 	},
+	{ // 6. Test add rests to query with relation selection
+		Query:     "ID,items{sku?qty>2?sku,desc}??",
+		SortField: "ID",
+		Limit:     "10",
+		RespQuery: "ID,items{sku?qty>2?sku,desc}??ID,,10,",
+	},
 }
 
 func TestAddQueryRestrictions(t *testing.T) {
@@ -712,3 +926,13 @@ func TestAddQueryRestrictions(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkGetConditionsList(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := GetConditionsList(TestModel{}, "?(ID>>1,2,3)*content==testText*count!=2?", true, false)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package compiler
+
+import (
+	"encoding/base64"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var (
+	relationNameRegexp  = regexp.MustCompile(`!?[a-zA-Z_][a-zA-Z0-9_]*$`)
+	relationBlockRegexp = regexp.MustCompile(`^!?[a-zA-Z_][a-zA-Z0-9_]*\{[a-zA-Z0-9_-]*\}$`)
+)
+
+// relationTag is a tag of nested struct or slice field declaring relation (join:"authors,author_id=id")
+const relationTag = "join"
+
+// relation describes related model, which target is joined to query only when its fields are referenced
+type relation struct {
+	name       string  // API name of relation (author)
+	alias      string  // alias of joined target (q_author)
+	target     string  // joined table or view
+	localKey   string  // column of model target
+	foreignKey string  // column of joined target
+	schema     *Schema // schema of related model
+	isMany     bool    // one-to-many relation, which is filtered by EXISTS subquery instead of join
+}
+
+// schemaRelation describes declared relation of model
+type schemaRelation struct {
+	name      string
+	modelType reflect.Type
+	target    string
+	on        string // join keys - local_column=foreign_column
+	isMany    bool
+}
+
+// WithRelation declares one-to-one or many-to-one relation of model. Fields of related model are referenced
+// with dotted names (author.name), on sets join keys as local_column=foreign_column (author_id=id),
+// foreign column is id by default. Relations are also declared with join tag of nested struct field (join:"authors,author_id=id")
+func WithRelation(name string, model interface{}, target, on string) SchemaOption {
+	return func(c *schemaConfig) {
+		c.relations = append(c.relations, schemaRelation{name: name, modelType: reflect.TypeOf(model), target: target, on: on})
+	}
+}
+
+// WithHasMany declares one-to-many relation of model, which is used in relation conditions (items{sku==X}).
+// On sets keys as local_column=foreign_column (id=order_id), local column is id by default
+func WithHasMany(name string, model interface{}, target, on string) SchemaOption {
+	return func(c *schemaConfig) {
+		c.relations = append(c.relations, schemaRelation{name: name, modelType: reflect.TypeOf(model), target: target, on: on, isMany: true})
+	}
+}
+
+// parseRelationTag reads relation declared with join tag of struct field, slice field declares one-to-many relation
+func parseRelationTag(name string, fieldType reflect.Type, tag string) (schemaRelation, error) {
+	parts := strings.Split(tag, ",")
+	if len(parts) != 2 {
+		return schemaRelation{}, newError("Passed unexpected relation tag - " + tag)
+	}
+
+	r := schemaRelation{name: name, modelType: fieldType, target: parts[0], on: parts[1]}
+	if r.modelType.Kind() == reflect.Ptr {
+		r.modelType = r.modelType.Elem()
+	}
+	if r.modelType.Kind() == reflect.Slice {
+		r.modelType, r.isMany = r.modelType.Elem(), true
+	}
+	if r.modelType.Kind() == reflect.Ptr {
+		r.modelType = r.modelType.Elem()
+	}
+	if r.modelType.Kind() != reflect.Struct {
+		return schemaRelation{}, newError("Passed unexpected relation tag - " + tag)
+	}
+
+	return r, nil
+}
+
+// addRelations forms schemas of related models. Relations of related models are not read
+func (s *Schema) addRelations(strict bool) error {
+	if s.config.isRelated {
+		return nil
+	}
+
+	for _, r := range s.config.relations {
+		if !columnNameRegexp.MatchString(r.name) || r.target == "" {
+			return newError("Passed unexpected relation - " + r.name)
+		}
+		if _, ok := s.fieldsMap[r.name]; ok || s.relation(r.name) != nil {
+			return newError("Passed model with duplicate field name - " + r.name)
+		}
+
+		keys := strings.Split(r.on, "=")
+		if len(keys) == 1 && r.isMany { // foreign column references id of model
+			keys = []string{"id", keys[0]}
+		} else if len(keys) == 1 {
+			keys = append(keys, "id")
+		}
+		if len(keys) != 2 || !columnNameRegexp.MatchString(keys[0]) || !columnNameRegexp.MatchString(keys[1]) {
+			return newError("Passed unexpected relation keys - " + r.on)
+		}
+
+		modelType := r.modelType
+		if modelType != nil && modelType.Kind() == reflect.Ptr {
+			modelType = modelType.Elem()
+		}
+		config := schemaConfig{
+			nameTag:      s.config.nameTag,
+			columnTag:    s.config.columnTag,
+			columnNaming: s.config.columnNaming,
+			isRelated:    true,
+		}
+		related, err := newSchema(modelType, r.target, config, strict)
+		if err != nil {
+			return err
+		}
+
+		s.relations = append(s.relations, &relation{
+			name:       r.name,
+			alias:      "q_" + r.name,
+			target:     r.target,
+			localKey:   keys[0],
+			foreignKey: keys[1],
+			schema:     related,
+			isMany:     r.isMany,
+		})
+	}
+
+	return nil
+}
+
+// relation returns relation by API name, nil means unexpected relation
+func (s *Schema) relation(name string) *relation {
+	for _, r := range s.relations {
+		if r.name == name {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// relationColumn returns relation and column of related model field of dotted name (author.name),
+// nil relation means the name is not a field of relation
+func (s *Schema) relationColumn(name string) (*relation, string) {
+	i := strings.Index(name, ".")
+	if i < 0 {
+		return nil, ""
+	}
+	r := s.relation(name[:i])
+	if r == nil || r.isMany { // fields of one-to-many relation would multiply rows
+		return nil, ""
+	}
+
+	field := name[i+1:]
+	if _, ok := r.schema.exprs[field]; ok { // computed fields of related model reference its own target
+		return nil, ""
+	}
+	column := r.schema.fieldsMap[field]
+	if column == "" {
+		return nil, ""
+	}
+
+	return r, column
+}
+
+// combineJoins assembles LEFT JOIN clauses of relations, which aliases are referenced in passed query parts
+func combineJoins(s *Schema, parts ...string) string {
+	var joins string
+	for _, r := range s.relations {
+		if r.isMany {
+			continue
+		}
+		for _, part := range parts {
+			if strings.Contains(part, r.alias+".") {
+				joins = joins + " left join " + r.target + " " + r.alias + " on " + r.alias + "." + r.foreignKey + " = q." + r.localKey
+				break
+			}
+		}
+	}
+
+	return joins
+}
+
+// encodeRelationBlocks replaces contents of relation blocks of query (items{sku==X*qty>2}) with encoded string without spaces,
+// so separators of the relation block are not parsed as separators of the query. Braces of condition values are left as is
+func encodeRelationBlocks(conds string) (string, error) {
+	return replaceRelationBlocks(conds, func(contents string) (string, error) {
+		return base64.RawURLEncoding.EncodeToString([]byte(strings.ReplaceAll(contents, " ", ""))), nil
+	})
+}
+
+// decodeRelationBlocks restores contents of relation blocks encoded by encodeRelationBlocks
+func decodeRelationBlocks(q string) (string, error) {
+	return replaceRelationBlocks(q, func(contents string) (string, error) {
+		data, err := base64.RawURLEncoding.DecodeString(contents)
+		if err != nil {
+			return "", newError("Passed unexpected relation block - " + contents)
+		}
+		return string(data), nil
+	})
+}
+
+// replaceRelationBlocks replaces contents of every relation block of query with result of replace
+func replaceRelationBlocks(conds string, replace func(contents string) (string, error)) (string, error) {
+	var result strings.Builder
+	for pos := 0; ; {
+		start := strings.Index(conds[pos:], "{")
+		if start < 0 {
+			result.WriteString(conds[pos:])
+			return result.String(), nil
+		}
+		start += pos
+
+		name := relationNameRegexp.FindString(conds[pos:start])
+		nameStart := start - len(name)
+		if name == "" || (nameStart > 0 && !strings.ContainsAny(conds[nameStart-1:nameStart], "*|(,? ")) {
+			result.WriteString(conds[pos : start+1]) // brace of condition value
+			pos = start + 1
+			continue
+		}
+
+		depth, end := 0, -1
+		for i := start; i < len(conds) && end < 0; i++ {
+			switch conds[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end < 0 {
+			return "", newError("Passed unclosed relation block - " + name)
+		}
+
+		contents, err := replace(conds[start+1 : end])
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(conds[pos : start+1])
+		result.WriteString(contents)
+		result.WriteString("}")
+		pos = end + 1
+	}
+}
+
+// isRelationBlock reports whether token is a relation block with encoded contents
+func isRelationBlock(token string) bool {
+	return relationBlockRegexp.MatchString(token)
+}
+
+// decodeRelationBlock splits relation block (items{...}) into relation name and decoded contents
+func decodeRelationBlock(token string) (name, contents string, err error) {
+	start := strings.Index(token, "{")
+	data, err := base64.RawURLEncoding.DecodeString(token[start+1 : len(token)-1])
+	if err != nil {
+		return "", "", newError("Passed unexpected relation block - " + token)
+	}
+
+	return token[:start], string(data), nil
+}
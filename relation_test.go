@@ -0,0 +1,148 @@
+package compiler
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type TestWriterModel struct {
+	ID        *int64     `json:"ID,omitempty" sql:"id"`
+	Name      *string    `json:"name,omitempty" sql:"name"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" sql:"created_at"`
+}
+
+type TestPublisherModel struct {
+	Code  *string `json:"code,omitempty" sql:"code"`
+	Title *string `json:"title,omitempty" sql:"title"`
+}
+
+type TestBookModel struct {
+	ID            *int64           `json:"ID,omitempty" sql:"id"`
+	Title         *string          `json:"title,omitempty" sql:"title"`
+	AuthorID      *int64           `json:"authorID,omitempty" sql:"author_id"`
+	PublisherCode *string          `json:"publisherCode,omitempty" sql:"publisher_code"`
+	Author        *TestWriterModel `json:"author,omitempty" join:"authors,author_id=id"`
+}
+
+var testRelationCases = []struct {
+	// Compile params
+	Opts   []Option
+	Params string
+
+	// Compile response
+	MainQuery  string
+	CountQuery string
+	Args       []interface{}
+	Err        error
+}{
+	{ // 1. Test relations are not joined without referenced fields
+		Params:    "ID,title?ID>1?",
+		MainQuery: "select q.id, q.title from v_books q where q.id > $1",
+		Args:      []interface{}{1},
+	},
+	{ // 2. Test related field in select and conditions
+		Params:    "ID,author.name?author.name==Ivan?",
+		MainQuery: "select q.id, q_author.name as author_name from v_books q left join authors q_author on q_author.id = q.author_id where q_author.name = $1",
+		Args:      []interface{}{"Ivan"},
+	},
+	{ // 3. Test related field in sort fields with count query
+		Opts:       []Option{WithCount(CountExact)},
+		Params:     "ID?ID>1?author.name,asc,10,",
+		MainQuery:  "select q.id from v_books q left join authors q_author on q_author.id = q.author_id where q.id > $1 order by q_author.name asc limit 10",
+		CountQuery: "select count(*) from (select 1 from v_books q left join authors q_author on q_author.id = q.author_id where q.id > $1) q",
+		Args:       []interface{}{1},
+	},
+	{ // 4. Test relation declared on registration
+		Params:    "ID,publisher.title?publisher.code==abc?",
+		MainQuery: "select q.id, q_publisher.title as publisher_title from v_books q left join publishers q_publisher on q_publisher.code = q.publisher_code where q_publisher.code = $1",
+		Args:      []interface{}{"abc"},
+	},
+	{ // 5. Test aggregates and buckets of related fields
+		Params:    "author.createdAt:year,ID:count??author.createdAt:year,desc,,?author.createdAt:year",
+		MainQuery: "select date_trunc('year', q_author.created_at) as author_created_at_year, count(q.id) as id_count from v_books q left join authors q_author on q_author.id = q.author_id group by date_trunc('year', q_author.created_at) order by date_trunc('year', q_author.created_at) desc",
+	},
+	{ // 6. Test ERROR unexpected related field
+		Params: "ID?author.title==1?",
+		Err:    newError("Passed unexpected field name in condition - author.title"),
+	},
+}
+
+func TestRelations(t *testing.T) {
+	s, err := Register(TestBookModel{}, "v_books", WithRelation("publisher", TestPublisherModel{}, "publishers", "publisher_code=code"))
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+
+	for index, c := range testRelationCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			res, err := New(c.Opts...).Compile(context.Background(), Request{Model: s, Params: c.Params})
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if res.MainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, res.MainQuery)
+				t.Fail()
+			}
+			if res.CountQuery != c.CountQuery {
+				t.Errorf("expected countQ: %v, got: %v", c.CountQuery, res.CountQuery)
+				t.Fail()
+			}
+			if !reflect.DeepEqual(res.Args, c.Args) {
+				t.Errorf("expected args: %v, got: %v", c.Args, res.Args)
+				t.Fail()
+			}
+		})
+	}
+}
+
+var testRegisterRelationCases = []struct {
+	// Register params
+	Opts []SchemaOption
+
+	// Register response
+	Err error
+}{
+	{ // 1. Test relation with default foreign key
+		Opts: []SchemaOption{WithRelation("publisher", TestPublisherModel{}, "publishers", "publisher_code")},
+	},
+	{ // 2. Test ERROR relation keys
+		Opts: []SchemaOption{WithRelation("publisher", TestPublisherModel{}, "publishers", "publisher_code=code=id")},
+		Err:  newError("Passed unexpected relation keys - publisher_code=code=id"),
+	},
+	{ // 3. Test ERROR relation name of model field
+		Opts: []SchemaOption{WithRelation("title", TestPublisherModel{}, "publishers", "publisher_code=code")},
+		Err:  newError("Passed model with duplicate field name - title"),
+	},
+	{ // 4. Test ERROR relation without target
+		Opts: []SchemaOption{WithRelation("publisher", TestPublisherModel{}, "", "publisher_code=code")},
+		Err:  newError("Passed unexpected relation - publisher"),
+	},
+}
+
+func TestRegisterRelation(t *testing.T) {
+	for index, c := range testRegisterRelationCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			_, err := Register(TestBookModel{}, "v_books", c.Opts...)
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+			}
+		})
+	}
+}
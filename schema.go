@@ -0,0 +1,460 @@
+package compiler
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Types of structs, which are read from database as a single value
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	nullTimeType = reflect.TypeOf(sql.NullTime{})
+	valuerType   = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType  = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// Schema describes model fields and target, validated once on registration
+type Schema struct {
+	Target string // target table or view of model
+
+	modelType  reflect.Type
+	fieldsMap  map[string]string // json tag: sql tag
+	exprs      map[string]string // json tag: sql expression of computed field
+	pk         []string          // json tags of primary key fields
+	timestamps map[string]bool   // json tags of timestamp fields
+	relations  []*relation       // relations joined by referenced fields
+	config     schemaConfig
+}
+
+// SchemaOption configures model reading on registration
+type SchemaOption func(*schemaConfig)
+
+// schemaConfig describes model reading rules
+type schemaConfig struct {
+	nestedPaths  bool
+	nameTag      string                   // tag of API field name
+	columnTag    string                   // tag of sql column name
+	columnNaming func(name string) string // column name for fields without column tag
+	expressions  []schemaExpression       // computed fields
+	primaryKey   []string                 // primary key fields instead of tagged ones
+	defaultSort  string                   // sort fields of query without ones
+	relations    []schemaRelation         // related models
+	pageLimits   PageLimits               // selection restrictions of model queries
+	isRelated    bool                     // schema of related model, which relations are not read
+}
+
+// schemaExpression describes API field backed by sql expression
+type schemaExpression struct {
+	name  string
+	alias string
+	expr  string
+}
+
+// defaultSchemaConfig returns rules of reading go-swagger models
+func defaultSchemaConfig() schemaConfig {
+	return schemaConfig{nameTag: "json", columnTag: "sql"}
+}
+
+// WithNameTag sets tag used for API field name, json by default
+func WithNameTag(tag string) SchemaOption {
+	return func(c *schemaConfig) {
+		c.nameTag = tag
+	}
+}
+
+// WithColumnTag sets tag used for sql column name, sql by default.
+// Tags in gorm format (column:name;type:text) are also supported
+func WithColumnTag(tag string) SchemaOption {
+	return func(c *schemaConfig) {
+		c.columnTag = tag
+	}
+}
+
+// WithColumnNaming sets naming strategy of sql column for fields without column tag (e.g. SnakeCase)
+func WithColumnNaming(naming func(name string) string) SchemaOption {
+	return func(c *schemaConfig) {
+		c.columnNaming = naming
+	}
+}
+
+// WithNestedPaths exposes fields of named nested structs as dotted paths (e.g. author.name),
+// sql column of such field is prefixed by sql tag of parent field
+func WithNestedPaths() SchemaOption {
+	return func(c *schemaConfig) {
+		c.nestedPaths = true
+	}
+}
+
+// WithExpression adds API field backed by sql expression, which is selected as "expr as alias"
+// and used as is in conditions and sort clauses. Target columns are referenced with q alias (e.g. coalesce(q.name, q.login))
+func WithExpression(name, alias, expr string) SchemaOption {
+	return func(c *schemaConfig) {
+		c.expressions = append(c.expressions, schemaExpression{name: name, alias: alias, expr: expr})
+	}
+}
+
+// WithPrimaryKey sets unique fields of model, which are appended to sort fields of paginated query
+// as a tiebreaker. By default fields with pk option of column tag (sql:"id,pk") or gorm primaryKey are used
+func WithPrimaryKey(fields ...string) SchemaOption {
+	return func(c *schemaConfig) {
+		c.primaryKey = fields
+	}
+}
+
+// WithDefaultSort sets sort fields of query without ones in restrictions format (createdAt:desc|ID)
+func WithDefaultSort(sort string) SchemaOption {
+	return func(c *schemaConfig) {
+		c.defaultSort = sort
+	}
+}
+
+// WithSchemaPageLimits sets selection restrictions of model queries, which are applied by Get and Search
+// and by compiler without own page limits. Schema.Limit and Schema.Offset return restricted values
+func WithSchemaPageLimits(limits PageLimits) SchemaOption {
+	return func(c *schemaConfig) {
+		c.pageLimits = limits
+	}
+}
+
+// Register validates model tags and forms its schema for reuse in compile and query-editing functions
+func Register(model interface{}, target string, opts ...SchemaOption) (*Schema, error) {
+	config := defaultSchemaConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if err := config.pageLimits.validate(); err != nil {
+		return nil, err
+	}
+
+	return newSchema(reflect.TypeOf(model), target, config, true)
+}
+
+// Get builds a GET query with parameters to schema target
+func (s *Schema) Get(params string, withCount, withArgs bool) (mainQ, countQ string, args []interface{}, er error) {
+	return Get(s, s.Target, params, withCount, withArgs)
+}
+
+// Search builds a GET query with LIKE filter on searchField to schema target
+func (s *Schema) Search(params string, withCount, withArgs bool, searchParams string) (mainQ, countQ string, args []interface{}, er error) {
+	return Search(s, s.Target, params, withCount, withArgs, searchParams)
+}
+
+// Limit returns selection limit from query with page limits of schema applied
+func (s *Schema) Limit(q string) (limit *int, err error) {
+	return s.config.pageLimits.Limit(q)
+}
+
+// Offset returns selection offset from query, checked against page limits of schema
+func (s *Schema) Offset(q string) (offset *int, err error) {
+	return s.config.pageLimits.Offset(q)
+}
+
+// Fields returns sorted list of schema json field names
+func (s *Schema) Fields() []string {
+	return sortMap(s.fieldsMap)
+}
+
+// newSchema forms schema of model type, strict mode reports invalid tags
+func newSchema(modelType reflect.Type, target string, config schemaConfig, strict bool) (*Schema, error) {
+	if modelType == nil {
+		return nil, newError("Model not passed")
+	}
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() != reflect.Struct {
+		return nil, newError("Passed model is not a struct - " + modelType.String())
+	}
+
+	s := &Schema{
+		Target:     target,
+		modelType:  modelType,
+		fieldsMap:  make(map[string]string, modelType.NumField()),
+		timestamps: make(map[string]bool),
+		config:     config,
+	}
+	visited := map[reflect.Type]bool{modelType: true}
+	if err := s.readFields(modelType, "", "", visited, strict); err != nil {
+		return nil, err
+	}
+	if err := s.addExpressions(); err != nil {
+		return nil, err
+	}
+	if err := s.addRelations(strict); err != nil {
+		return nil, err
+	}
+	if err := s.addSortRules(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// addSortRules validates primary key and default sort fields of schema
+func (s *Schema) addSortRules() error {
+	if s.config.primaryKey != nil {
+		s.pk = s.config.primaryKey
+	}
+	for _, f := range s.pk {
+		if s.fieldExpr(f) == "" {
+			return newError("Passed unexpected primary key field - " + f)
+		}
+	}
+
+	if s.config.defaultSort != "" {
+		for _, token := range strings.Split(s.config.defaultSort, "|") {
+			f, _, _, err := parseSortToken(token, "asc")
+			if err != nil {
+				return err
+			}
+			if s.fieldExpr(f) == "" {
+				return newError("Passed unexpected default sort field - " + f)
+			}
+		}
+	}
+
+	return nil
+}
+
+// addExpressions adds computed fields into schema
+func (s *Schema) addExpressions() error {
+	s.exprs = make(map[string]string, len(s.config.expressions))
+	for _, e := range s.config.expressions {
+		if e.name == "" || e.expr == "" {
+			return newError("Passed empty computed field")
+		}
+		if !columnNameRegexp.MatchString(e.alias) {
+			return newError("Passed unexpected alias of computed field - " + e.alias)
+		}
+		if _, ok := s.fieldsMap[e.name]; ok {
+			return newError("Passed model with duplicate field name - " + e.name)
+		}
+
+		s.fieldsMap[e.name] = e.alias
+		s.exprs[e.name] = e.expr
+	}
+
+	return nil
+}
+
+// fieldExpr returns sql expression of API field, empty string means unexpected field
+func (s *Schema) fieldExpr(name string) string {
+	if expr, ok := s.exprs[name]; ok {
+		return expr
+	}
+	if column := s.fieldsMap[name]; column != "" {
+		return "q." + column
+	}
+	if r, column := s.relationColumn(name); r != nil {
+		return r.alias + "." + column
+	}
+
+	return ""
+}
+
+// selectExpr returns select list element of API field, empty string means unexpected field
+func (s *Schema) selectExpr(name string) string {
+	if expr, ok := s.exprs[name]; ok {
+		return expr + " as " + s.fieldsMap[name]
+	}
+	if r, _ := s.relationColumn(name); r != nil && s.fieldsMap[name] == "" {
+		return s.fieldExpr(name) + " as " + s.columnAlias(name)
+	}
+
+	return s.fieldExpr(name)
+}
+
+// columnAlias returns column name of API field in result rows, fields of related model are prefixed by relation name
+func (s *Schema) columnAlias(name string) string {
+	if column := s.fieldsMap[name]; column != "" {
+		return column
+	}
+	if r, column := s.relationColumn(name); r != nil {
+		return r.name + "_" + column
+	}
+
+	return ""
+}
+
+// isTimestamp reports whether API field is a timestamp field of model or related model
+func (s *Schema) isTimestamp(name string) bool {
+	if s.timestamps[name] {
+		return true
+	}
+	if r, _ := s.relationColumn(name); r != nil && s.fieldsMap[name] == "" {
+		return r.schema.timestamps[name[len(r.name)+1:]]
+	}
+
+	return false
+}
+
+// readFields reads fields tags of struct type into schema. Embedded structs are merged into model,
+// named nested structs are handled as single column or as dotted paths with nestedPaths option
+func (s *Schema) readFields(structType reflect.Type, namePrefix, columnPrefix string, visited map[reflect.Type]bool, strict bool) error {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" && !field.Anonymous { // skip unexported field
+			continue
+		}
+
+		name := tagName(field.Tag.Get(s.config.nameTag))
+		if name == "-" {
+			continue
+		}
+		column := tagColumn(field.Tag.Get(s.config.columnTag))
+
+		nestedType := field.Type
+		if nestedType.Kind() == reflect.Ptr {
+			nestedType = nestedType.Elem()
+		}
+		if tag := field.Tag.Get(relationTag); tag != "" && name != "" { // handle related model
+			r, err := parseRelationTag(namePrefix+name, field.Type, tag)
+			if err != nil {
+				return err
+			}
+			s.config.relations = append(s.config.relations, r)
+			continue
+		}
+		if nestedType.Kind() == reflect.Struct && !isValueStruct(nestedType) { // handle nested struct
+			nestedNamePrefix, nestedColumnPrefix := namePrefix, columnPrefix
+			switch {
+			case field.Anonymous && name == "": // embedded struct fields are merged into model
+			case name != "" && s.config.nestedPaths: // named struct fields are exposed as dotted paths
+				nestedNamePrefix = namePrefix + name + "."
+				if column != "" {
+					nestedColumnPrefix = columnPrefix + column + "_"
+				} else if s.config.columnNaming != nil {
+					nestedColumnPrefix = columnPrefix + s.config.columnNaming(name) + "_"
+				}
+			case column == "": // named struct without sql tag is merged into model
+			default: // named struct with sql tag is a single column (e.g. JSONB)
+				nestedType = nil
+			}
+
+			if nestedType != nil {
+				if visited[nestedType] { // skip recursive struct
+					continue
+				}
+
+				visited[nestedType] = true
+				err := s.readFields(nestedType, nestedNamePrefix, nestedColumnPrefix, visited, strict)
+				delete(visited, nestedType)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if name == "" { // untagged field is not a part of query
+			continue
+		}
+		if column == "" && s.config.columnNaming != nil {
+			column = s.config.columnNaming(name)
+		}
+		if strict {
+			if column == "" {
+				return newError("Passed model field without " + s.config.columnTag + " tag - " + structType.Name() + "." + field.Name)
+			}
+			if _, ok := s.fieldsMap[namePrefix+name]; ok {
+				return newError("Passed model with duplicate field name - " + namePrefix + name)
+			}
+		}
+
+		s.fieldsMap[namePrefix+name] = columnPrefix + column
+		if isPrimaryKeyTag(field.Tag.Get(s.config.columnTag)) {
+			s.pk = append(s.pk, namePrefix+name)
+		}
+		if isTimestampType(field.Type) {
+			s.timestamps[namePrefix+name] = true
+		}
+	}
+
+	return nil
+}
+
+// tagName returns field name from tag value
+func tagName(tag string) string {
+	if i := strings.Index(tag, ","); i >= 0 {
+		tag = tag[:i]
+	}
+
+	return tag
+}
+
+// tagColumn returns column name from tag value, including gorm format (column:name;type:text)
+func tagColumn(tag string) string {
+	if strings.Contains(tag, ":") {
+		for _, setting := range strings.Split(tag, ";") {
+			kv := strings.SplitN(setting, ":", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "column" {
+				return strings.TrimSpace(kv[1])
+			}
+		}
+		return ""
+	}
+
+	return tagName(tag)
+}
+
+// isPrimaryKeyTag reports if column tag marks primary key field (id,pk or gorm column:id;primaryKey)
+func isPrimaryKeyTag(tag string) bool {
+	for _, setting := range strings.Split(tag, ";") { // gorm format
+		switch strings.ToLower(strings.TrimSpace(setting)) {
+		case "primarykey", "primary_key":
+			return true
+		}
+	}
+
+	for _, option := range strings.Split(tag, ",")[1:] {
+		if strings.TrimSpace(option) == "pk" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SnakeCase converts field name to snake case column name (createdAt -> created_at, userID -> user_id)
+func SnakeCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			isWordStart := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])))
+			if isWordStart {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		} else if r == '.' || r == '-' {
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// isValueStruct reports if struct is read from database as a single value
+func isValueStruct(structType reflect.Type) bool {
+	if structType == timeType {
+		return true
+	}
+
+	return structType.Implements(valuerType) || reflect.PtrTo(structType).Implements(scannerType)
+}
+
+// isTimestampType reports whether field type is a timestamp - time.Time, sql.NullTime or type based on time.Time (strfmt.DateTime)
+func isTimestampType(fieldType reflect.Type) bool {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	return fieldType == nullTimeType || (fieldType.Kind() == reflect.Struct && fieldType.ConvertibleTo(timeType))
+}
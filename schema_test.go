@@ -0,0 +1,461 @@
+package compiler
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+type TestDuplicateModel struct {
+	ID *int64 `json:"ID,omitempty" sql:"id"`
+	TestDuplicateNestedModel
+}
+
+type TestDuplicateNestedModel struct {
+	NestedID *int64 `json:"ID,omitempty" sql:"nested_id"`
+}
+
+type TestUntaggedModel struct {
+	ID      *int64  `json:"ID,omitempty" sql:"id"`
+	Content *string `json:"content,omitempty"`
+}
+
+type TestBaseModel struct {
+	ID        *int64     `json:"ID,omitempty" sql:"id"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" sql:"created_at"`
+}
+
+type TestAuthorModel struct {
+	Name  *string `json:"name,omitempty" sql:"name"`
+	Email *string `json:"email,omitempty" sql:"email"`
+}
+
+type TestComplexModel struct {
+	*TestBaseModel
+	Title     *string          `json:"title,omitempty" sql:"title"`
+	UpdatedAt time.Time        `json:"updatedAt" sql:"updated_at"`
+	Author    *TestAuthorModel `json:"author,omitempty" sql:"author"`
+	Hidden    *string          `json:"-" sql:"hidden"`
+	Internal  *string
+	Parent    *TestComplexModel `json:"parent,omitempty"`
+}
+
+type TestSqlxModel struct {
+	ID        *int64  `json:"ID,omitempty" db:"id"`
+	UserName  *string `json:"userName,omitempty" db:"user_name,omitempty"`
+	CreatedAt *string `json:"createdAt,omitempty"`
+}
+
+type TestGormModel struct {
+	ID    *int64  `json:"ID,omitempty" gorm:"column:id;primaryKey"`
+	Title *string `json:"title,omitempty" gorm:"type:text;column:title_text"`
+}
+
+type TestPkModel struct {
+	ID        *int64  `json:"ID,omitempty" sql:"id,pk"`
+	Title     *string `json:"title,omitempty" sql:"title"`
+	CreatedAt *string `json:"createdAt,omitempty" sql:"created_at"`
+}
+
+var testRegisterCases = []struct {
+	// Register params
+	Model  interface{}
+	Target string
+	Opts   []SchemaOption
+
+	// Register response
+	Fields []string
+	Err    error
+}{
+	{ // 1. Test model with nested struct
+		Model:  TestModel{},
+		Target: "v_test",
+		Fields: []string{"ID", "content", "count", "extraField", "isBool", "oneMoreField"},
+	},
+	{ // 2. Test pointer to model
+		Model:  &TestModel{},
+		Target: "v_test",
+		Fields: []string{"ID", "content", "count", "extraField", "isBool", "oneMoreField"},
+	},
+	{ // 3. Test ERROR map model
+		Model:  map[string]string{"ID": "id"},
+		Target: "v_test",
+		Err:    newError("Passed model is not a struct - map[string]string"),
+	},
+	{ // 4. Test ERROR nil model
+		Model:  nil,
+		Target: "v_test",
+		Err:    newError("Model not passed"),
+	},
+	{ // 5. Test ERROR duplicate json name in nested struct
+		Model:  TestDuplicateModel{},
+		Target: "v_test",
+		Err:    newError("Passed model with duplicate field name - ID"),
+	},
+	{ // 6. Test ERROR field without sql tag
+		Model:  TestUntaggedModel{},
+		Target: "v_test",
+		Err:    newError("Passed model field without sql tag - TestUntaggedModel.Content"),
+	},
+	{ // 7. Test embedded pointer, time and named nested struct with sql tag
+		Model:  TestComplexModel{},
+		Target: "v_test",
+		Fields: []string{"ID", "author", "createdAt", "title", "updatedAt"},
+	},
+	{ // 8. Test named nested struct as dotted paths
+		Model:  TestComplexModel{},
+		Target: "v_test",
+		Opts:   []SchemaOption{WithNestedPaths()},
+		Fields: []string{"ID", "author.email", "author.name", "createdAt", "title", "updatedAt"},
+	},
+	{ // 9. Test ERROR sqlx model without naming strategy
+		Model:  TestSqlxModel{},
+		Target: "v_test",
+		Opts:   []SchemaOption{WithColumnTag("db")},
+		Err:    newError("Passed model field without db tag - TestSqlxModel.CreatedAt"),
+	},
+}
+
+var testColumnTagsCases = []struct {
+	// Register params
+	Model interface{}
+	Opts  []SchemaOption
+
+	// Schema response
+	Query     string
+	MainQuery string
+}{
+	{ // 1. Test sqlx db tags with snake case naming strategy
+		Model:     TestSqlxModel{},
+		Opts:      []SchemaOption{WithColumnTag("db"), WithColumnNaming(SnakeCase)},
+		Query:     "??",
+		MainQuery: "select q.id, q.created_at, q.user_name from v_test q",
+	},
+	{ // 2. Test gorm column tags
+		Model:     TestGormModel{},
+		Opts:      []SchemaOption{WithColumnTag("gorm")},
+		Query:     "title?ID==1?",
+		MainQuery: "select q.title_text from v_test q where q.id = 1",
+	},
+	{ // 3. Test snake case naming strategy by API names of json tags
+		Model:     TestGormModel{},
+		Opts:      []SchemaOption{WithColumnTag("sql"), WithColumnNaming(SnakeCase)},
+		Query:     "ID,title??",
+		MainQuery: "select q.id, q.title from v_test q",
+	},
+	{ // 4. Test custom name tag
+		Model:     TestGormModel{},
+		Opts:      []SchemaOption{WithNameTag("gorm"), WithColumnTag("gorm")},
+		Query:     "??",
+		MainQuery: "select q.id, q.title_text from v_test q",
+	},
+}
+
+func TestColumnTags(t *testing.T) {
+	for index, c := range testColumnTagsCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			s, err := Register(c.Model, "v_test", c.Opts...)
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			mainQuery, _, _, err := s.Get(c.Query, false, false)
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+			if mainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, mainQuery)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"ID":           "id",
+		"createdAt":    "created_at",
+		"userID":       "user_id",
+		"HTTPServer":   "http_server",
+		"oneMoreField": "one_more_field",
+		"field2Name":   "field2_name",
+	}
+	for name, expected := range cases {
+		if column := SnakeCase(name); column != expected {
+			t.Errorf("expected column: %v, got: %v", expected, column)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	for index, c := range testRegisterCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			s, err := Register(c.Model, c.Target, c.Opts...)
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if !isSlicesEqual(s.Fields(), c.Fields) {
+				t.Errorf("expected fields: %v, got: %v", c.Fields, s.Fields())
+				t.Fail()
+			}
+			if s.Target != c.Target {
+				t.Errorf("expected target: %v, got: %v", c.Target, s.Target)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestNestedPaths(t *testing.T) {
+	s, err := Register(TestComplexModel{}, "v_test", WithNestedPaths())
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+
+	mainQuery, _, _, err := s.Get("ID,author.name?author.name==Ivan?createdAt,desc,,", false, false)
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+	expected := "select q.id, q.author_name from v_test q where q.author_name = Ivan order by q.created_at desc"
+	if mainQuery != expected {
+		t.Errorf("expected mainQ: %v, got: %v", expected, mainQuery)
+	}
+}
+
+var testExpressionsCases = []struct {
+	// Get params
+	Params       string
+	SearchParams string
+	WithArgs     bool
+
+	// Get response
+	MainQuery string
+	Args      []interface{}
+	Err       error
+}{
+	{ // 1. Test computed fields in select block
+		Params:    "ID,year,login??",
+		MainQuery: "select q.id, extract(year from q.created_at) as created_year, lower(q.content) as login from v_test q",
+	},
+	{ // 2. Test computed field in conditions and restrictions blocks
+		Params:    "ID?year>=2020*login==admin?year,desc,10,",
+		WithArgs:  true,
+		MainQuery: "select q.id from v_test q where extract(year from q.created_at) >= $1 and lower(q.content) = $2 order by extract(year from q.created_at) desc limit 10",
+		Args:      []interface{}{2020, "admin"},
+	},
+	{ // 3. Test computed field in search condition
+		Params:       "ID??",
+		SearchParams: "login~~adm",
+		MainQuery:    "select q.id from v_test q where (lower(lower(q.content)::text) like %adm%)",
+	},
+}
+
+func TestExpressions(t *testing.T) {
+	s, err := Register(TestModel{}, "v_test",
+		WithExpression("year", "created_year", "extract(year from q.created_at)"),
+		WithExpression("login", "login", "lower(q.content)"),
+	)
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+
+	for index, c := range testExpressionsCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			mainQuery, _, args, err := s.Search(c.Params, false, c.WithArgs, c.SearchParams)
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+
+			if mainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, mainQuery)
+				t.Fail()
+			}
+			if c.WithArgs {
+				for i, v := range args {
+					if c.Args[i] != v {
+						t.Errorf("expected arg: %v, got: %v", c.Args[i], v)
+						t.Fail()
+					}
+				}
+			}
+		})
+	}
+
+	fields, err := GetFieldsList(s, "ID,year??")
+	if err != nil || !isSlicesEqual(fields, []string{"id", "created_year"}) {
+		t.Errorf("expected fields: %v, got: %v (err: %v)", []string{"id", "created_year"}, fields, err)
+	}
+
+	_, err = Register(TestModel{}, "v_test", WithExpression("ID", "id2", "q.id + 1"))
+	if err == nil || err.Error() != newError("Passed model with duplicate field name - ID").Error() {
+		t.Errorf("expected err: %v, got: %v", newError("Passed model with duplicate field name - ID"), err)
+	}
+	_, err = Register(TestModel{}, "v_test", WithExpression("next", "next id", "q.id + 1"))
+	if err == nil || err.Error() != newError("Passed unexpected alias of computed field - next id").Error() {
+		t.Errorf("expected err: %v, got: %v", newError("Passed unexpected alias of computed field - next id"), err)
+	}
+}
+
+func TestSchemaGet(t *testing.T) {
+	s, err := Register(TestModel{}, "v_test")
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+
+	mainQuery, countQuery, args, err := s.Get("ID?ID==1?", true, true)
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+	if mainQuery != "select q.id from v_test q where q.id = $1" {
+		t.Errorf("expected mainQ: %v, got: %v", "select q.id from v_test q where q.id = $1", mainQuery)
+	}
+	if countQuery != "select count(*) from (select 1 from v_test q where q.id = $1) q" {
+		t.Errorf("expected countQ: %v, got: %v", "select count(*) from (select 1 from v_test q where q.id = $1) q", countQuery)
+	}
+	if len(args) != 1 || args[0] != 1 {
+		t.Errorf("expected args: %v, got: %v", []interface{}{1}, args)
+	}
+
+	fields, err := GetFieldsList(s, "ID,isBool??")
+	if err != nil {
+		t.Fatalf("expected err: %v, got: %v", nil, err)
+	}
+	if !isSlicesEqual(fields, []string{"id", "is_bool"}) {
+		t.Errorf("expected fields: %v, got: %v", []string{"id", "is_bool"}, fields)
+	}
+}
+
+func TestGetInvalidModel(t *testing.T) {
+	_, _, _, err := Get(map[string]string{"ID": "id"}, "v_test", "ID??", false, false)
+	if err == nil || err.Error() != newError("Passed model is not a struct - map[string]string").Error() {
+		t.Errorf("expected err: %v, got: %v", newError("Passed model is not a struct - map[string]string"), err)
+	}
+
+	mainQuery, _, _, err := Get(&TestModel{}, "v_test", "ID??", false, false)
+	if err != nil || mainQuery != "select q.id from v_test q" {
+		t.Errorf("expected mainQ: %v, got: %v (err: %v)", "select q.id from v_test q", mainQuery, err)
+	}
+}
+
+var testSortRulesCases = []struct {
+	// Get params
+	Model  interface{}
+	Opts   []SchemaOption
+	Params string
+
+	// Get response
+	MainQuery string
+	Err       error
+}{
+	{ // 1. Test tiebreaker is appended to paginated query
+		Model:     TestPkModel{},
+		Params:    "ID??createdAt,desc,10,",
+		MainQuery: "select q.id from v_test q order by q.created_at desc, q.id desc limit 10",
+	},
+	{ // 2. Test tiebreaker is not duplicated
+		Model:     TestPkModel{},
+		Params:    "ID??ID|title,,10,20",
+		MainQuery: "select q.id from v_test q order by q.id asc, q.title asc limit 10 offset 20",
+	},
+	{ // 3. Test query without pagination is not changed
+		Model:     TestPkModel{},
+		Params:    "ID??title,desc,,",
+		MainQuery: "select q.id from v_test q order by q.title desc",
+	},
+	{ // 4. Test tiebreaker of paginated query without sort fields
+		Model:     TestPkModel{},
+		Params:    "ID??,,10,",
+		MainQuery: "select q.id from v_test q order by q.id asc limit 10",
+	},
+	{ // 5. Test default sort with tiebreaker
+		Model:     TestPkModel{},
+		Opts:      []SchemaOption{WithDefaultSort("createdAt:desc:nullslast")},
+		Params:    "ID??,,10,",
+		MainQuery: "select q.id from v_test q order by q.created_at desc nulls last, q.id desc limit 10",
+	},
+	{ // 6. Test default sort without pagination
+		Model:     TestPkModel{},
+		Opts:      []SchemaOption{WithDefaultSort("title")},
+		Params:    "ID??",
+		MainQuery: "select q.id from v_test q order by q.title asc",
+	},
+	{ // 7. Test primary key option and gorm primary key tag
+		Model:     TestGormModel{},
+		Opts:      []SchemaOption{WithColumnTag("gorm")},
+		Params:    "ID??title,asc,5,",
+		MainQuery: "select q.id from v_test q order by q.title_text asc, q.id asc limit 5",
+	},
+	{ // 8. Test primary key option
+		Model:     TestModel{},
+		Opts:      []SchemaOption{WithPrimaryKey("ID")},
+		Params:    "ID??count,desc,5,",
+		MainQuery: "select q.id from v_test q order by q.count desc, q.id desc limit 5",
+	},
+	{ // 9. Test ERROR unexpected primary key field
+		Model: TestModel{},
+		Opts:  []SchemaOption{WithPrimaryKey("title")},
+		Err:   newError("Passed unexpected primary key field - title"),
+	},
+	{ // 10. Test ERROR unexpected default sort field
+		Model: TestPkModel{},
+		Opts:  []SchemaOption{WithDefaultSort("updatedAt:desc")},
+		Err:   newError("Passed unexpected default sort field - updatedAt"),
+	},
+	{ // 11. Test distinct selection without not selected tiebreaker
+		Model:     TestPkModel{},
+		Params:    "distinct,title??title,asc,10,",
+		MainQuery: "select distinct q.title from v_test q order by q.title asc limit 10",
+	},
+	{ // 12. Test distinct selection with selected tiebreaker
+		Model:     TestPkModel{},
+		Params:    "distinct,ID,title??title,asc,10,",
+		MainQuery: "select distinct q.id, q.title from v_test q order by q.title asc, q.id asc limit 10",
+	},
+	{ // 13. Test distinct selection without not selected default sort field
+		Model:     TestPkModel{},
+		Opts:      []SchemaOption{WithDefaultSort("createdAt:desc")},
+		Params:    "distinct,title??,,10,",
+		MainQuery: "select distinct q.title from v_test q limit 10",
+	},
+	{ // 14. Test ERROR distinct selection with not selected order field
+		Model:  TestPkModel{},
+		Params: "distinct,title??createdAt,asc,10,",
+		Err:    newError("Passed not selected order field in distinct selection - createdAt"),
+	},
+}
+
+func TestSortRules(t *testing.T) {
+	for index, c := range testSortRulesCases {
+		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
+			s, err := Register(c.Model, "v_test", c.Opts...)
+			mainQuery := ""
+			if err == nil {
+				mainQuery, _, _, err = s.Get(c.Params, false, true)
+			}
+			if c.Err != nil {
+				if err == nil || err.Error() != c.Err.Error() {
+					t.Errorf("expected err: %v, got: %v", c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected err: %v, got: %v", nil, err)
+				t.FailNow()
+			}
+			if mainQuery != c.MainQuery {
+				t.Errorf("expected mainQ: %v, got: %v", c.MainQuery, mainQuery)
+			}
+		})
+	}
+}